@@ -0,0 +1,442 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Копирование статических файлов: директории static/ и файлов рядом со страницами.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// assetManifestKey — ключ JSON файлов с отпечатком в манифесте сборки
+const assetManifestKey = "/assets"
+
+// assetHashLength — число шестнадцатеричных знаков отпечатка в имени файла: app.3f9a1c2e.css
+const assetHashLength = 8
+
+// integrityHashes — алгоритмы хэшей Subresource Integrity
+var integrityHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// assetTask — файл, который копируется в директорию сборки
+type assetTask struct {
+	Source string // Путь к исходному файлу
+	Output string // Путь результата относительно директории сборки, через "/"
+}
+
+// isPageFile сообщает, что файл директории страницы описывает саму страницу и не копируется
+func isPageFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".val", ".md", ".setting":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+// pageAssetDir возвращает директорию файлов страницы в сборке: директорию её index.html
+// или путь результата без .html (about.html -> about/)
+func pageAssetDir(model *Model) string {
+	if path.Base(model.Path) == "index.html" {
+		return path.Dir(model.Path)
+	}
+	return strings.TrimSuffix(model.Path, ".html")
+}
+
+// staticAssets возвращает файлы директории staticDir с сохранением структуры.
+// Файлы и директории, начинающиеся с точки, пропускаются. Отсутствие директории не является ошибкой.
+func staticAssets(staticDir string) ([]assetTask, error) {
+	var tasks []assetTask
+	err := filepath.Walk(staticDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == staticDir {
+				return nil
+			}
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && p != staticDir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(staticDir, p)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, assetTask{Source: p, Output: filepath.ToSlash(rel)})
+		return nil
+	})
+	return tasks, err
+}
+
+// assetUnchanged сообщает, что файл результата совпадает с исходным по размеру и времени изменения.
+// copyAsset переносит время изменения исходника на копию, поэтому повторная сборка её не трогает.
+// Размер минифицированной копии отличается от исходного, поэтому при minified сравнивается только время.
+func assetUnchanged(source os.FileInfo, output string, minified bool) bool {
+	info, err := os.Stat(output)
+	return err == nil && !info.IsDir() && (minified || info.Size() == source.Size()) && info.ModTime().Equal(source.ModTime())
+}
+
+// copyAsset копирует файл source в output, если копия устарела или задан force, а при minify
+// записывает минифицированное содержимое. Возвращает true, если файл записан.
+func copyAsset(source, output string, minify, force bool) (bool, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return false, err
+	}
+	if !force && assetUnchanged(info, output, minify) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return false, err
+	}
+	if minify {
+		content, err := ioutil.ReadFile(source)
+		if err != nil {
+			return false, err
+		}
+		if err := ioutil.WriteFile(output, minifyAsset(source, content), 0644); err != nil {
+			return false, err
+		}
+		return true, os.Chtimes(output, info.ModTime(), info.ModTime())
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	out, err := os.Create(output)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return false, err
+	}
+	if err := out.Close(); err != nil {
+		return false, err
+	}
+	return true, os.Chtimes(output, info.ModTime(), info.ModTime())
+}
+
+// staticFile — файл static/ и его путь в сборке
+type staticFile struct {
+	Source    string `json:"-"`
+	File      string `json:"file"`                // Путь результата, с отпечатком, если файл подходит под assets.fingerprint
+	Integrity string `json:"integrity,omitempty"` // Хэш SRI, вычисленный при первом обращении
+	hashed    bool   // Хэши вычислены при создании индекса
+	minify    bool   // Файл копируется минифицированным, и отпечатки считаются по результату
+
+	hashOnce sync.Once // Вычисление хэша SRI при первом обращении к файлу без отпечатка
+	hashErr  error
+}
+
+// assetIndex — файлы static/ одной сборки, к которым обращаются {asset} и {integrity}.
+// Набор файлов не меняется после newAssetIndex, поэтому читается из разных горутин без блокировки.
+type assetIndex struct {
+	files     map[string]*staticFile // Путь относительно static/ через "/"
+	integrity string
+}
+
+// newAssetIndex обходит static/ и вычисляет отпечатки файлов, подходящих под assets.fingerprint.
+// Файлы без отпечатка не читаются, пока шаблон не запросит их хэш SRI.
+func newAssetIndex(opts *Options) (*assetIndex, error) {
+	tasks, err := staticAssets(opts.StaticDir)
+	if err != nil {
+		return nil, err
+	}
+	cfg := opts.Config.Assets
+	index := &assetIndex{files: make(map[string]*staticFile, len(tasks)), integrity: cfg.Integrity}
+	for _, task := range tasks {
+		file := &staticFile{Source: task.Source, File: task.Output, minify: opts.minifiesAsset(task.Source)}
+		if matchAsset(cfg.Fingerprint, task.Output) {
+			sum, integrity, err := hashAsset(task.Source, cfg.Integrity, file.minify)
+			if err != nil {
+				return nil, err
+			}
+			file.File = fingerprintName(task.Output, sum)
+			file.Integrity, file.hashed = integrity, true
+		}
+		index.files[task.Output] = file
+	}
+	return index, nil
+}
+
+// matchAsset сообщает, подходит ли путь name под один из шаблонов. Шаблон без "/" сравнивается
+// с именем файла (*.css), шаблон с "/" — с путём целиком (js/*.js).
+func matchAsset(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hashAsset читает файл один раз и возвращает отпечаток для имени и, если задан algorithm, хэш SRI.
+// При minify хэши считаются по минифицированному содержимому, которое и попадёт в сборку.
+func hashAsset(source, algorithm string, minify bool) (string, string, error) {
+	var in io.Reader
+	if minify {
+		content, err := ioutil.ReadFile(source)
+		if err != nil {
+			return "", "", err
+		}
+		in = bytes.NewReader(minifyAsset(source, content))
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return "", "", err
+		}
+		defer f.Close()
+		in = f
+	}
+	sum := sha256.New()
+	writers := []io.Writer{sum}
+	var sri hash.Hash
+	if newHash, ok := integrityHashes[algorithm]; ok {
+		sri = newHash()
+		writers = append(writers, sri)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), in); err != nil {
+		return "", "", err
+	}
+	integrity := ""
+	if sri != nil {
+		integrity = algorithm + "-" + base64.StdEncoding.EncodeToString(sri.Sum(nil))
+	}
+	return hex.EncodeToString(sum.Sum(nil))[:assetHashLength], integrity, nil
+}
+
+// fingerprintName вставляет отпечаток перед расширением: css/app.css -> css/app.3f9a1c2e.css
+func fingerprintName(name, sum string) string {
+	ext := path.Ext(name)
+	if ext == path.Base(name) {
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + "." + sum + ext
+}
+
+// resolve возвращает адрес файла name от корня сайта или, при integrity, его хэш SRI.
+// Пустой индекс (nil) не содержит файлов.
+func (ix *assetIndex) resolve(name string, integrity bool) (string, error) {
+	if ix == nil {
+		return "", fmt.Errorf("файл %s не найден в static/", name)
+	}
+	file, ok := ix.files[name]
+	if !ok {
+		return "", fmt.Errorf("файл %s не найден в static/", name)
+	}
+	if !integrity {
+		return "/" + file.File, nil
+	}
+	// Файл читается один раз; страницы, которым нужны другие файлы, не ждут его хэширования
+	file.hashOnce.Do(func() {
+		if !file.hashed {
+			_, file.Integrity, file.hashErr = hashAsset(file.Source, ix.integrity, file.minify)
+		}
+	})
+	if file.hashErr != nil {
+		return "", file.hashErr
+	}
+	return file.Integrity, nil
+}
+
+// inputs возвращает значения {asset} и {integrity}, которые использует шаблон со всей цепочкой {extends}
+// и блоки, для манифеста сборки: страница рендерится заново, если у файла изменился отпечаток.
+// Ненайденный файл записывается пустым значением, а ошибку сообщает рендеринг.
+func (ix *assetIndex) inputs(t *Template, blocks *BlockSet) map[string]string {
+	inputs := make(map[string]string)
+	add := func(refs []string) {
+		for _, ref := range refs {
+			i := strings.IndexByte(ref, ':')
+			inputs[ref], _ = ix.resolve(ref[i+1:], ref[:i] == "integrity")
+		}
+	}
+	for tpl := t; tpl != nil; tpl = tpl.parent {
+		add(tpl.assets)
+	}
+	for name := range blocks.fingerprint(t) {
+		add(blocks.templates[name].assets)
+	}
+	return inputs
+}
+
+// digest возвращает отпечаток значений {asset} и {integrity} шаблона коллекции и всех блоков
+func (ix *assetIndex) digest(t *Template, blocks *BlockSet) string {
+	inputs := make(map[string]string)
+	for _, refs := range [][]string{t.assets, blocks.allAssets()} {
+		for _, ref := range refs {
+			i := strings.IndexByte(ref, ':')
+			inputs[ref], _ = ix.resolve(ref[i+1:], ref[:i] == "integrity")
+		}
+	}
+	data, _ := json.Marshal(inputs)
+	return contentHash(string(data))
+}
+
+// allAssets возвращает ссылки на файлы static/ во всех блоках
+func (bs *BlockSet) allAssets() []string {
+	var refs []string
+	for _, tpl := range bs.templates {
+		refs = append(refs, tpl.assets...)
+	}
+	return refs
+}
+
+// fingerprinted возвращает файлы с отпечатком для assets.manifest
+func (ix *assetIndex) fingerprinted() map[string]*staticFile {
+	files := make(map[string]*staticFile)
+	for name, file := range ix.files {
+		if file.File != name {
+			files[name] = file
+		}
+	}
+	return files
+}
+
+// claimAssetManifest закрепляет путь assets.manifest в owners, если отпечатки включены. Если путь
+// уже занят страницей или файлом, конфликт попадает в отчёт и манифест не записывается.
+func claimAssetManifest(opts *Options, report *BuildReport, owners map[string]string) bool {
+	if len(opts.Config.Assets.Fingerprint) == 0 {
+		return false
+	}
+	name := opts.Config.Assets.Manifest
+	if owner, taken := owners[name]; taken {
+		report.addError(stageAsset, name, fmt.Errorf("путь %s уже занят: %s", name, owner))
+		return false
+	}
+	owners[name] = "манифест файлов"
+	return true
+}
+
+// writeAssetManifest записывает assets.manifest с путями файлов с отпечатком, если отпечатки включены
+// и путь манифеста свободен
+func writeAssetManifest(assets *assetIndex, opts *Options, report *BuildReport, prev, next *BuildManifest, owners map[string]string) {
+	if !claimAssetManifest(opts, report, owners) {
+		return
+	}
+	data, err := json.MarshalIndent(assets.fingerprinted(), "", "  ")
+	if err != nil {
+		report.addError(stageAsset, opts.Config.Assets.Manifest, err)
+		return
+	}
+	name := opts.Config.Assets.Manifest
+	entry := &ManifestCategory{Fingerprint: contentHash(name + "\x00" + string(data)), Outputs: []string{name}}
+	if old := prev.Categories[assetManifestKey]; old != nil && old.Fingerprint == entry.Fingerprint && outputsExist(opts.OutputDir, old.Outputs) {
+		next.setCategory(assetManifestKey, old)
+		return
+	}
+	if err := writeCollectionFile(opts, name, data); err != nil {
+		report.addError(stageAsset, name, err)
+		if old := prev.Categories[assetManifestKey]; old != nil {
+			next.setCategory(assetManifestKey, &ManifestCategory{Outputs: old.Outputs})
+		}
+		return
+	}
+	next.setCategory(assetManifestKey, entry)
+}
+
+// assetTasks возвращает задачи копирования файлов static/ и файлов страниц и закрепляет их пути
+// в owners. Пути страниц уже в owners, затем их получают файлы static/ и файлы страниц.
+// Файл, путь которого занят страницей или другим файлом, пропускается и попадает в отчёт как ошибка.
+func assetTasks(models []*Model, assets *assetIndex, report *BuildReport, owners map[string]string) []assetTask {
+	names := make([]string, 0, len(assets.files))
+	for name := range assets.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	candidates := make([]assetTask, 0, len(names))
+	for _, name := range names {
+		candidates = append(candidates, assetTask{Source: assets.files[name].Source, Output: assets.files[name].File})
+	}
+	for _, model := range models {
+		dir := pageAssetDir(model)
+		for _, source := range model.Assets {
+			candidates = append(candidates, assetTask{Source: source, Output: path.Join(dir, filepath.Base(source))})
+		}
+	}
+
+	tasks := candidates[:0]
+	for _, task := range candidates {
+		if owner, taken := owners[task.Output]; taken {
+			report.addError(stageAsset, task.Source, fmt.Errorf("путь %s уже занят: %s", task.Output, owner))
+			continue
+		}
+		owners[task.Output] = task.Source
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// copyAssets копирует static/ и файлы страниц в директорию сборки пулом из build.writers воркеров.
+// Файлы static/ с отпечатком копируются только под именем с отпечатком, CSS и JavaScript при minify.enabled
+// минифицируются. После смены настроек или с -force все файлы копируются заново.
+// Файл, путь которого занят страницей или другим файлом, не копируется и попадает в отчёт как ошибка.
+// Файлы, удалённые из исходников с прошлой сборки, удаляются из директории сборки.
+func copyAssets(models []*Model, assets *assetIndex, opts *Options, report *BuildReport, prev, next *BuildManifest, owners map[string]string) {
+	tasks := assetTasks(models, assets, report, owners)
+	chTasks := make(chan assetTask, len(tasks))
+	for _, task := range tasks {
+		chTasks <- task
+	}
+	close(chTasks)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Config.Build.Writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range chTasks {
+				output := filepath.Join(opts.OutputDir, filepath.FromSlash(task.Output))
+				copied, err := copyAsset(task.Source, output, opts.minifiesAsset(task.Source), prev.stale)
+				if err != nil {
+					report.addError(stageAsset, task.Source, err)
+					// Прежняя копия не удаляется, пока файл не удастся скопировать
+					if old, ok := prev.Assets[task.Output]; ok {
+						next.setAsset(task.Output, old)
+					}
+					continue
+				}
+				if copied {
+					report.assetCopied()
+				}
+				next.setAsset(task.Output, task.Source)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range prev.removeStaleAssets(next, opts.OutputDir) {
+		report.addError(stageAsset, opts.OutputDir, err)
+	}
+	writeAssetManifest(assets, opts, report, prev, next, owners)
+}
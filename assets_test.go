@@ -0,0 +1,183 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты копирования статических файлов и файлов страниц.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyAssetSkipsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "static", "site.css")
+	output := filepath.Join(dir, "build", "css", "site.css")
+	writeTestFile(t, source, "body{}")
+	setTime := func(d int) {
+		mtime := time.Date(2025, 7, d, 0, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(source, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setTime(1)
+
+	steps := []struct {
+		name   string
+		change func()
+		force  bool
+		copied bool
+	}{
+		{"первое копирование", func() {}, false, true},
+		{"без изменений", func() {}, false, false},
+		{"новое время изменения", func() { setTime(2) }, false, true},
+		{"новый размер при том же времени", func() { writeTestFile(t, source, "body{margin:0}"); setTime(2) }, false, true},
+		{"принудительно", func() {}, true, true},
+	}
+	for _, step := range steps {
+		step.change()
+		copied, err := copyAsset(source, output, false, step.force)
+		if err != nil {
+			t.Fatalf("%s: ошибка %v", step.name, err)
+		}
+		if copied != step.copied {
+			t.Errorf("%s: скопирован %v, ожидалось %v", step.name, copied, step.copied)
+		}
+	}
+	if data, err := os.ReadFile(output); err != nil || string(data) != "body{margin:0}" {
+		t.Errorf("копия %q, ошибка %v", data, err)
+	}
+}
+
+func TestAssetTasksConflicts(t *testing.T) {
+	dir := t.TempDir()
+	opts := &Options{Config: defaultConfig(), StaticDir: filepath.Join(dir, "static")}
+	writeTestFile(t, filepath.Join(opts.StaticDir, "css", "site.css"), "body{}")
+	writeTestFile(t, filepath.Join(opts.StaticDir, "about.html"), "<p>x</p>")
+	writeTestFile(t, filepath.Join(opts.StaticDir, "about", "photo.jpg"), "jpg")
+	writeTestFile(t, filepath.Join(opts.StaticDir, ".hidden"), "x")
+	assets, err := newAssetIndex(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &Model{
+		ID:     "about",
+		Path:   "about.html",
+		Assets: []string{filepath.Join(dir, "content", "about", "photo.jpg"), filepath.Join(dir, "content", "about", "map.png")},
+	}
+	owners := map[string]string{"about.html": "страница about"}
+	report := newBuildReport(false)
+
+	var outputs []string
+	for _, task := range assetTasks([]*Model{page}, assets, report, owners) {
+		outputs = append(outputs, task.Output)
+	}
+	if got := strings.Join(outputs, ","); got != "about/photo.jpg,css/site.css,about/map.png" {
+		t.Errorf("копируются %s", got)
+	}
+	var causes []string
+	for _, issue := range report.Errors {
+		causes = append(causes, issue.Cause)
+	}
+	want := []string{
+		"путь about.html уже занят: страница about",
+		"путь about/photo.jpg уже занят: " + filepath.Join(opts.StaticDir, "about", "photo.jpg"),
+	}
+	if strings.Join(causes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ошибки %q, ожидались %q", causes, want)
+	}
+	if owners["css/site.css"] != filepath.Join(opts.StaticDir, "css", "site.css") {
+		t.Errorf("путь файла не закреплён: %v", owners)
+	}
+}
+
+func TestClaimAssetManifest(t *testing.T) {
+	opts := &Options{Config: defaultConfig()}
+	owners := map[string]string{"assets.json": "static/assets.json"}
+	report := newBuildReport(false)
+	if claimAssetManifest(opts, report, owners) || len(report.Errors) > 0 {
+		t.Errorf("манифест без отпечатков: ошибки %+v", report.Errors)
+	}
+
+	opts.Config.Assets.Fingerprint = []string{"*.css"}
+	if claimAssetManifest(opts, report, owners) {
+		t.Error("занятый путь манифеста закреплён")
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Cause != "путь assets.json уже занят: static/assets.json" {
+		t.Errorf("ошибки %+v", report.Errors)
+	}
+
+	opts.Config.Assets.Manifest = "asset-map.json"
+	if !claimAssetManifest(opts, report, owners) || owners["asset-map.json"] != "манифест файлов" {
+		t.Errorf("свободный путь манифеста не закреплён: %v", owners)
+	}
+}
+
+func TestAssetIndexResolveConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	opts := &Options{Config: defaultConfig(), StaticDir: filepath.Join(dir, "static")}
+	opts.Config.Assets.Fingerprint = []string{"*.css"}
+	writeTestFile(t, filepath.Join(opts.StaticDir, "site.css"), "body{}")
+	writeTestFile(t, filepath.Join(opts.StaticDir, "app.js"), "x()")
+	assets, err := newAssetIndex(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Хэш файла без отпечатка вычисляется при первом обращении, затем файл удаляется:
+	// остальные обращения получают тот же результат без повторного чтения
+	first, err := assets.resolve("app.js", true)
+	if err != nil || !strings.HasPrefix(first, "sha384-") {
+		t.Fatalf("integrity app.js = %q, ошибка %v", first, err)
+	}
+	if err := os.Remove(filepath.Join(opts.StaticDir, "app.js")); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 64)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, ref := range []struct {
+				name      string
+				integrity bool
+			}{{"app.js", true}, {"site.css", true}, {"site.css", false}, {"app.js", false}} {
+				got, err := assets.resolve(ref.name, ref.integrity)
+				switch {
+				case err != nil:
+					errs <- err.Error()
+				case ref.name == "app.js" && ref.integrity && got != first:
+					errs <- "integrity app.js = " + got
+				case !ref.integrity && !strings.HasPrefix(got, "/"):
+					errs <- "адрес " + got
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if _, err := assets.resolve("missing.css", false); err == nil {
+		t.Error("ненайденный файл не вернул ошибку")
+	}
+}
@@ -0,0 +1,614 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Интерфейс командной строки goferret: подкоманды и флаги директорий.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Коды завершения программы
+const (
+	exitOK          = 0 // Сборка прошла успешно
+	exitBuildFailed = 1 // Сборка или проверка завершилась с ошибками
+	exitUsage       = 2 // Неверные аргументы или отсутствуют директории
+)
+
+// Сообщения интерфейса командной строки
+const (
+	msgUsage = `Использование: goferret <команда> [флаги]
+
+Команды:
+  build              собрать сайт (команда по умолчанию)
+  new page <id>      создать заготовку новой страницы в директории content
+  check              проверить страницы и шаблоны без записи файлов
+  serve              собрать сайт, раздавать директорию build по HTTP и пересобирать при изменениях
+  watch              собрать сайт и пересобирать его при изменении исходников
+  help               показать эту справку
+
+Запустите "goferret <команда> -h", чтобы увидеть флаги команды.
+`
+	msgUnknownCommand    = "Ошибка: неизвестная команда %q\n"
+	msgUnknownNewKind    = "Ошибка: неизвестный тип %q, поддерживается только \"new page <id>\"\n"
+	msgPageIDRequired    = "Ошибка: не указан идентификатор страницы (goferret new page <id>)"
+	msgPageAlreadyExists = "Ошибка: страница %s уже существует: %s\n"
+	msgInvalidPageID     = "Ошибка: недопустимый идентификатор страницы %q: %v\n"
+	msgErrorCreatingPage = "Ошибка при создании страницы %s: %v\n"
+	msgPageCreated       = "Создана страница: %s\n"
+	msgServing           = "Сайт доступен по адресу http://localhost:%d/ (директория %s)\n"
+	msgErrorServing      = "Ошибка HTTP-сервера: %v\n"
+	msgErrorConfig       = "Ошибка конфигурации: %v\n"
+)
+
+// Options содержит параметры запуска: корень исходников, пути ко всем директориям,
+// поведение при ошибках и конфигурацию сайта, прочитанную из goferret.json или goferret.toml
+type Options struct {
+	Root           string
+	TemplatesDir   string
+	ContentDir     string
+	BlocksDir      string
+	CollectionsDir string
+	StaticDir      string
+	OutputDir      string
+	ConfigPath     string
+	Config         *SiteConfig
+	KeepGoing      bool
+	FailFast       bool
+	ReportPath     string
+	Force          bool
+	Drafts         bool
+	Future         bool
+
+	outputFlag string // Значение -output до применения конфигурации
+	outputSet  bool   // -output задан явно и важнее build.output
+}
+
+// dirFlag связывает директорию с флагом, через который она задаётся, для сообщений об ошибках
+type dirFlag struct {
+	Flag     string
+	Path     string
+	NotFound string
+}
+
+// newFlagSet создаёт набор флагов подкоманды с общими флагами директорий
+func newFlagSet(name string, opts *Options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.Root, "root", ".", "корневая директория исходников сайта")
+	fs.StringVar(&opts.TemplatesDir, "templates", "templates", "директория шаблонов страниц (относительно -root)")
+	fs.StringVar(&opts.ContentDir, "content", "content", "директория содержимого (относительно -root)")
+	fs.StringVar(&opts.BlocksDir, "blocks", "blocks", "директория глобальных блоков (относительно -root)")
+	fs.StringVar(&opts.CollectionsDir, "collections", "collections", "директория шаблонов коллекций (относительно -root)")
+	fs.StringVar(&opts.StaticDir, "static", "static", "директория статических файлов, копируемых в сборку (относительно -root)")
+	fs.StringVar(&opts.OutputDir, "output", "build", "директория для сгенерированных файлов (относительно -root)")
+	fs.StringVar(&opts.ConfigPath, "config", "", "файл конфигурации (по умолчанию goferret.json или goferret.toml в -root)")
+	return fs
+}
+
+// addReportFlags добавляет флаги, управляющие реакцией на ошибки и отчётом о сборке
+func addReportFlags(fs *flag.FlagSet, opts *Options) {
+	fs.BoolVar(&opts.KeepGoing, "keep-going", true, "продолжать сборку после ошибок и сообщить обо всех в конце")
+	fs.BoolVar(&opts.FailFast, "fail-fast", false, "остановить сборку после первой ошибки")
+	fs.StringVar(&opts.ReportPath, "report-json", "", "записать отчёт о сборке в JSON-файл (\"-\" — стандартный вывод)")
+}
+
+// addBuildFlags добавляет флаги сборки сайта
+func addBuildFlags(fs *flag.FlagSet, opts *Options) {
+	fs.BoolVar(&opts.Force, "force", false, "собрать все страницы заново, не используя манифест предыдущей сборки")
+	addPublishFlags(fs, opts)
+}
+
+// addPublishFlags добавляет флаги, которые определяют, какие страницы попадают в сборку
+func addPublishFlags(fs *flag.FlagSet, opts *Options) {
+	fs.BoolVar(&opts.Drafts, "drafts", false, "включить в сборку черновики (draft = true)")
+	fs.BoolVar(&opts.Future, "future", false, "включить в сборку страницы с датой публикации в будущем")
+}
+
+// addWatchFlags добавляет флаги опроса исходников для команд watch и serve
+func addWatchFlags(fs *flag.FlagSet) (interval, debounce *time.Duration) {
+	interval = fs.Duration("interval", 500*time.Millisecond, "период опроса файлов")
+	debounce = fs.Duration("debounce", 300*time.Millisecond, "пауза после последнего изменения перед пересборкой")
+	return interval, debounce
+}
+
+// stopOnError сообщает, нужно ли прерывать сборку на первой ошибке
+func (opts *Options) stopOnError() bool {
+	return opts.FailFast || !opts.KeepGoing
+}
+
+// messageOutput возвращает поток для сообщений о ходе сборки.
+// Если JSON-отчёт пишется в стандартный вывод, сообщения уходят в stderr.
+func (opts *Options) messageOutput() io.Writer {
+	if opts.ReportPath == "-" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// finishReport печатает отчёт, при необходимости сохраняет его в JSON и возвращает код завершения
+func finishReport(report *BuildReport, opts *Options) int {
+	report.print(opts.messageOutput())
+	if opts.ReportPath != "" {
+		if err := report.writeJSON(opts.ReportPath); err != nil {
+			fmt.Fprintf(os.Stderr, msgErrorReport, opts.ReportPath, err)
+			return exitBuildFailed
+		}
+	}
+	if report.Failed() {
+		return exitBuildFailed
+	}
+	return exitOK
+}
+
+// resolvePath делает относительный путь относительным к Root
+func (opts *Options) resolvePath(dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(opts.Root, dir)
+}
+
+// resolvePaths делает относительные пути директорий относительными к Root
+func (opts *Options) resolvePaths() {
+	opts.TemplatesDir = opts.resolvePath(opts.TemplatesDir)
+	opts.ContentDir = opts.resolvePath(opts.ContentDir)
+	opts.BlocksDir = opts.resolvePath(opts.BlocksDir)
+	opts.CollectionsDir = opts.resolvePath(opts.CollectionsDir)
+	opts.StaticDir = opts.resolvePath(opts.StaticDir)
+}
+
+// applyConfig устанавливает конфигурацию сайта и заново вычисляет зависящие от неё пути.
+// Вызывается при разборе флагов и при перечитывании конфигурации в режиме наблюдения.
+func (opts *Options) applyConfig(cfg *SiteConfig) {
+	opts.Config = cfg
+	output := opts.outputFlag
+	// Флаги командной строки имеют приоритет над файлом конфигурации
+	if !opts.outputSet && cfg.Build.Output != "" {
+		output = cfg.Build.Output
+	}
+	opts.OutputDir = opts.resolvePath(output)
+}
+
+// validateDirs проверяет, что обязательные директории существуют, и печатает ошибку для каждого флага
+func (opts *Options) validateDirs() bool {
+	required := []dirFlag{
+		{Flag: "-templates", Path: opts.TemplatesDir, NotFound: msgTemplatesDirNotFound},
+		{Flag: "-content", Path: opts.ContentDir, NotFound: msgContentDirNotFound},
+		{Flag: "-blocks", Path: opts.BlocksDir, NotFound: msgBlocksDirNotFound},
+	}
+	ok := true
+	for _, dir := range required {
+		info, err := os.Stat(dir.Path)
+		if err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, dir.NotFound, dir.Path, dir.Flag)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// parseFlags разбирает флаги подкоманды, читает конфигурацию сайта и готовит Options к использованию.
+// Возвращает код завершения, если выполнение нужно прекратить, или -1.
+func parseFlags(fs *flag.FlagSet, opts *Options, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	configPath := opts.ConfigPath
+	if configPath == "" {
+		found, err := findConfigFile(opts.Root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, msgErrorConfig, err)
+			return exitUsage
+		}
+		configPath = found
+	} else if !filepath.IsAbs(configPath) {
+		configPath = filepath.Join(opts.Root, configPath)
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msgErrorConfig, err)
+		return exitUsage
+	}
+	opts.ConfigPath = configPath
+
+	opts.outputFlag = opts.OutputDir
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "output" {
+			opts.outputSet = true
+		}
+	})
+	opts.resolvePaths()
+	opts.applyConfig(cfg)
+	return -1
+}
+
+// run разбирает аргументы командной строки, выполняет подкоманду и возвращает код завершения
+func run(args []string) int {
+	command := "build"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "build":
+		return cmdBuild(args)
+	case "new":
+		return cmdNew(args)
+	case "check":
+		return cmdCheck(args)
+	case "serve":
+		return cmdServe(args)
+	case "watch":
+		return cmdWatch(args)
+	case "help":
+		fmt.Print(msgUsage)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, msgUnknownCommand, command)
+		fmt.Fprint(os.Stderr, msgUsage)
+		return exitUsage
+	}
+}
+
+// cmdBuild выполняет команду build
+func cmdBuild(args []string) int {
+	opts := &Options{}
+	fs := newFlagSet("build", opts)
+	addReportFlags(fs, opts)
+	addBuildFlags(fs, opts)
+	if code := parseFlags(fs, opts, args); code >= 0 {
+		return code
+	}
+	if !opts.validateDirs() {
+		return exitUsage
+	}
+
+	report := runBuild(opts, nil)
+	code := finishReport(report, opts)
+	out := opts.messageOutput()
+	if code == exitOK {
+		fmt.Fprintln(out, msgSiteGenerationDone)
+	}
+	fmt.Fprintf(out, msgElapsed, report.DurationMs)
+	return code
+}
+
+// validatePageID проверяет, что страница pageID создаётся внутри директории контента:
+// идентификатор не может быть абсолютным путём и содержать элементы "."/"..".
+func validatePageID(pageID string) error {
+	if filepath.IsAbs(pageID) || filepath.VolumeName(pageID) != "" || strings.HasPrefix(filepath.ToSlash(pageID), "/") {
+		return fmt.Errorf("ожидается путь относительно директории контента")
+	}
+	for _, part := range strings.Split(filepath.ToSlash(pageID), "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("путь не может содержать пустые элементы, \".\" и \"..\"")
+		}
+	}
+	clean := filepath.Clean(pageID)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("путь выходит за пределы директории контента")
+	}
+	return nil
+}
+
+// cmdNew выполняет команду "new page <id>", создавая заготовку страницы
+func cmdNew(args []string) int {
+	if len(args) == 0 || args[0] != "page" {
+		kind := ""
+		if len(args) > 0 {
+			kind = args[0]
+		}
+		fmt.Fprintf(os.Stderr, msgUnknownNewKind, kind)
+		return exitUsage
+	}
+
+	opts := &Options{}
+	fs := newFlagSet("new page", opts)
+	title := fs.String("title", "", "заголовок страницы (по умолчанию совпадает с идентификатором)")
+	template := fs.String("template", "", "шаблон страницы (по умолчанию build.defaultTemplate или blog)")
+	category := fs.String("category", "", "категория страницы")
+	markdown := fs.Bool("markdown", false, "создать content.md вместо content.val")
+	single := fs.Bool("single", false, "создать однофайловую страницу <id>.md с front matter")
+
+	// Идентификатор страницы может стоять как до флагов, так и после них
+	rest := args[1:]
+	pageID := ""
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		pageID, rest = rest[0], rest[1:]
+	}
+	if code := parseFlags(fs, opts, rest); code >= 0 {
+		return code
+	}
+	if pageID == "" && fs.NArg() > 0 {
+		pageID = fs.Arg(0)
+	}
+	if pageID == "" {
+		fmt.Fprintln(os.Stderr, msgPageIDRequired)
+		return exitUsage
+	}
+	if err := validatePageID(pageID); err != nil {
+		fmt.Fprintf(os.Stderr, msgInvalidPageID, pageID, err)
+		return exitUsage
+	}
+	if info, err := os.Stat(opts.ContentDir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, msgContentDirNotFound, opts.ContentDir, "-content")
+		return exitUsage
+	}
+
+	pageDir := filepath.Join(opts.ContentDir, pageID)
+	pageFile := pageDir + ".md"
+	for _, existing := range []string{pageDir, pageFile} {
+		if _, err := os.Stat(existing); err == nil {
+			fmt.Fprintf(os.Stderr, msgPageAlreadyExists, pageID, existing)
+			return exitUsage
+		}
+	}
+	if *title == "" {
+		*title = pageID
+	}
+	if *template == "" {
+		*template = opts.Config.Build.DefaultTemplate
+	}
+	if *template == "" {
+		*template = "blog"
+	}
+
+	if *single {
+		if err := writePageFile(pageFile, *title, *template, *category); err != nil {
+			fmt.Fprintf(os.Stderr, msgErrorCreatingPage, pageID, err)
+			return exitBuildFailed
+		}
+		fmt.Printf(msgPageCreated, pageFile)
+		return exitOK
+	}
+
+	contentFile := "content.val"
+	if *markdown {
+		contentFile = "content.md"
+	}
+	files := map[string]string{
+		"title.val":        *title,
+		contentFile:        "",
+		"template.setting": *template,
+	}
+	if *category != "" {
+		files["category.val"] = *category
+	}
+	if err := os.MkdirAll(pageDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, msgErrorCreatingPage, pageID, err)
+		return exitBuildFailed
+	}
+	for name, value := range files {
+		if err := os.WriteFile(filepath.Join(pageDir, name), []byte(value), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, msgErrorCreatingPage, pageID, err)
+			return exitBuildFailed
+		}
+	}
+	fmt.Printf(msgPageCreated, pageDir)
+	return exitOK
+}
+
+// writePageFile создаёт однофайловую страницу с YAML front matter
+func writePageFile(path, title, template, category string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "title: %q\n", title)
+	fmt.Fprintf(&sb, "template: %s\n", template)
+	if category != "" {
+		fmt.Fprintf(&sb, "category: %s\n", category)
+	}
+	sb.WriteString("---\n\n")
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// cmdCheck выполняет команду check: обрабатывает все страницы и шаблоны, ничего не записывая
+func cmdCheck(args []string) int {
+	opts := &Options{}
+	fs := newFlagSet("check", opts)
+	addReportFlags(fs, opts)
+	addPublishFlags(fs, opts)
+	if code := parseFlags(fs, opts, args); code >= 0 {
+		return code
+	}
+	if !opts.validateDirs() {
+		return exitUsage
+	}
+
+	start := time.Now()
+	report := newBuildReport(opts.stopOnError())
+	checkSite(opts, report)
+	report.finish(start)
+	return finishReport(report, opts)
+}
+
+// checkSite последовательно обрабатывает страницы и рендерит их шаблоны в памяти. Страницы
+// отбираются и получают пути так же, как при сборке, а конфликты путей страниц, файлов static/
+// и терминов таксономий попадают в отчёт. В директорию сборки ничего не записывается.
+func checkSite(opts *Options, report *BuildReport) {
+	blocks, err := getBlocksSubModel(opts.BlocksDir)
+	if err != nil {
+		report.addError(stageBlocks, opts.BlocksDir, err)
+		return
+	}
+	for k, v := range opts.Config.templateVars() {
+		blocks.Data[k] = v
+	}
+	assets, err := newAssetIndex(opts)
+	if err != nil {
+		report.addError(stageAsset, opts.StaticDir, err)
+		return
+	}
+	blocks.assets = assets
+	pages, err := listPages(opts.ContentDir)
+	if err != nil {
+		report.addError(stageContent, opts.ContentDir, err)
+		return
+	}
+	report.Pages = len(pages)
+	templates := newTemplateSet(opts.TemplatesDir)
+
+	// Манифест без записи: в нём только пути результатов страниц для проверки конфликтов
+	next := newManifest("")
+	var outputs sync.Map
+	var models []*Model
+	now := time.Now()
+	for _, pagePath := range pages {
+		if report.stopped() {
+			return
+		}
+		model, err := processPage(opts.ContentDir, pagePath, blocks.Data, opts.Config.Build.MarkdownHTML)
+		if err != nil {
+			report.addError(stagePage, pageIDFromPath(opts.ContentDir, pagePath), err)
+			continue
+		}
+		included, err := preparePage(model, opts, now, &outputs)
+		if !included {
+			report.pageExcluded()
+			continue
+		}
+		if err != nil {
+			report.addError(stagePage, model.ID, err)
+			continue
+		}
+		if model.Template == "" {
+			report.addWarning(stageTemplate, model.ID, msgNoTemplate)
+			continue
+		}
+		tpl, templateVars, err := templates.loadTemplate(model.Template)
+		if err != nil {
+			report.addError(stageTemplate, model.ID, err)
+			continue
+		}
+		for k, v := range templateVars {
+			if _, exists := model.Data[k]; !exists {
+				model.Data[k] = v
+			}
+		}
+		if err := blocks.expand(model.Data, model.Safe); err != nil {
+			report.addError(stageRender, model.ID, err)
+			continue
+		}
+		if _, err := renderTemplate(tpl, model, assets); err != nil {
+			report.addError(stageRender, model.ID, err)
+			continue
+		}
+		next.setPage(model.ID, &ManifestPage{Output: model.Path})
+		models = append(models, model)
+	}
+	if report.stopped() {
+		return
+	}
+
+	owners := outputOwners(next)
+	assetTasks(models, assets, report, owners)
+	claimAssetManifest(opts, report, owners)
+	terms, termCount := taxonomyPlan(models, blocks, opts, report, newManifest(""), next, owners)
+	report.Categories = termCount
+	sitemapPlan(models, terms, opts, report, owners)
+}
+
+// cmdServe выполняет команду serve: собирает сайт, раздаёт директорию сборки по HTTP
+// и пересобирает сайт при изменениях, перезагружая открытые страницы
+func cmdServe(args []string) int {
+	opts := &Options{}
+	fs := newFlagSet("serve", opts)
+	addReportFlags(fs, opts)
+	addBuildFlags(fs, opts)
+	interval, debounce := addWatchFlags(fs)
+	port := fs.Int("port", 8080, "порт HTTP-сервера")
+	watch := fs.Bool("watch", true, "пересобирать сайт при изменении исходников")
+	liveReload := fs.Bool("live-reload", true, "перезагружать открытые страницы после пересборки")
+	if code := parseFlags(fs, opts, args); code >= 0 {
+		return code
+	}
+	if !opts.validateDirs() {
+		return exitUsage
+	}
+
+	server := &devServer{}
+	server.setRoot(opts.OutputDir)
+	if *watch && *liveReload {
+		server.reload = newReloadHub()
+	}
+	if *watch {
+		// Сервер запускается после первой сборки, а ошибки следующих сборок его не останавливают
+		built := make(chan struct{})
+		first := true
+		watcher := newSiteWatcher(opts, *interval, *debounce)
+		go watcher.run(nil, func(report *BuildReport) {
+			printWatchReport(report, opts)
+			// build.output мог измениться при перечитывании конфигурации
+			server.setRoot(opts.OutputDir)
+			if first {
+				first = false
+				close(built)
+			} else if server.reload != nil {
+				server.reload.broadcast()
+			}
+		})
+		<-built
+	} else if code := finishReport(runBuild(opts, nil), opts); code != exitOK {
+		return code
+	}
+
+	fmt.Printf(msgServing, *port, opts.OutputDir)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", *port), server); err != nil {
+		fmt.Fprintf(os.Stderr, msgErrorServing, err)
+		return exitBuildFailed
+	}
+	return exitOK
+}
+
+// cmdWatch выполняет команду watch: собирает сайт и пересобирает его при изменении
+// содержимого, шаблонов, блоков, коллекций или конфигурации
+func cmdWatch(args []string) int {
+	opts := &Options{}
+	fs := newFlagSet("watch", opts)
+	addReportFlags(fs, opts)
+	addBuildFlags(fs, opts)
+	interval, debounce := addWatchFlags(fs)
+	if code := parseFlags(fs, opts, args); code >= 0 {
+		return code
+	}
+	if !opts.validateDirs() {
+		return exitUsage
+	}
+
+	watcher := newSiteWatcher(opts, *interval, *debounce)
+	fmt.Printf(msgWatching, opts.Root)
+	watcher.run(nil, func(report *BuildReport) {
+		printWatchReport(report, opts)
+	})
+	return exitOK
+}
+
+// printWatchReport печатает отчёт очередной сборки в режиме наблюдения
+func printWatchReport(report *BuildReport, opts *Options) {
+	out := opts.messageOutput()
+	if finishReport(report, opts) == exitOK {
+		fmt.Fprintln(out, msgSiteGenerationDone)
+	} else {
+		fmt.Fprintln(out, msgWatchFailed)
+	}
+	fmt.Fprintf(out, msgElapsed, report.DurationMs)
+	// Манифест уже учитывает эту сборку, поэтому -force действует только на первую
+	opts.Force = false
+}
@@ -0,0 +1,131 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты команд командной строки.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatePageID(t *testing.T) {
+	tests := []struct {
+		id string
+		ok bool
+	}{
+		{"about", true},
+		{"docs/setup", true},
+		{"docs/setup.v2", true},
+		{"../x", false},
+		{"../../x", false},
+		{"docs/../../x", false},
+		{"docs/../x", false},
+		{"./x", false},
+		{"docs//x", false},
+		{"/etc/x", false},
+		{"..", false},
+	}
+	for _, tt := range tests {
+		if err := validatePageID(tt.id); (err == nil) != tt.ok {
+			t.Errorf("validatePageID(%q) = %v, ожидалось ok = %v", tt.id, err, tt.ok)
+		}
+	}
+}
+
+func TestApplyConfigOutput(t *testing.T) {
+	root := filepath.Join("site", "src")
+	tests := []struct {
+		name   string
+		args   []string
+		output string // build.output перечитанной конфигурации
+		want   string
+	}{
+		{"по умолчанию", nil, "", filepath.Join(root, "build")},
+		{"из конфигурации", nil, "public", filepath.Join(root, "public")},
+		{"абсолютный путь в конфигурации", nil, "/srv/www", "/srv/www"},
+		{"флаг важнее конфигурации", []string{"-output", "out"}, "public", filepath.Join(root, "out")},
+	}
+	for _, tt := range tests {
+		opts := &Options{}
+		fs := newFlagSet("build", opts)
+		if code := parseFlags(fs, opts, append([]string{"-root", root}, tt.args...)); code >= 0 {
+			t.Fatalf("%s: код %d", tt.name, code)
+		}
+		// Так конфигурацию перечитывает режим наблюдения
+		cfg := *opts.Config
+		cfg.Build.Output = tt.output
+		opts.applyConfig(&cfg)
+		if opts.OutputDir != tt.want {
+			t.Errorf("%s: OutputDir = %q, ожидалось %q", tt.name, opts.OutputDir, tt.want)
+		}
+		if want := filepath.Join(root, "content"); opts.ContentDir != want {
+			t.Errorf("%s: ContentDir = %q, ожидалось %q", tt.name, opts.ContentDir, want)
+		}
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("templates/page.tpl", "<h1>{title}</h1>")
+	write("blocks/footer.tpl", "<footer></footer>")
+	write("content/a/title.val", "A")
+	write("content/a/template.setting", "page")
+	reportPath := filepath.Join(root, "report.json")
+
+	steps := []struct {
+		name   string
+		change func()
+		args   []string
+		want   int
+	}{
+		{"успешная сборка", func() {}, []string{"build", "-root", root}, exitOK},
+		{"команда по умолчанию", func() {}, []string{"-root", root}, exitOK},
+		{"справка", func() {}, []string{"help"}, exitOK},
+		{"неизвестная команда", func() {}, []string{"deploy"}, exitUsage},
+		{"неизвестный флаг", func() {}, []string{"build", "-root", root, "-nope"}, exitUsage},
+		{"нет директории", func() {}, []string{"build", "-root", root, "-templates", "missing"}, exitUsage},
+		{"ошибка страницы", func() {
+			write("content/b/title.val", "B")
+			write("content/b/template.setting", "missing")
+		}, []string{"build", "-root", root, "-report-json", reportPath}, exitBuildFailed},
+		{"ошибка при проверке", func() {}, []string{"check", "-root", root}, exitBuildFailed},
+		{"-fail-fast", func() {}, []string{"build", "-root", root, "-fail-fast", "-report-json", reportPath}, exitBuildFailed},
+		{"ошибка записи отчёта", func() {
+			os.RemoveAll(filepath.Join(root, "content", "b"))
+		}, []string{"build", "-root", root, "-report-json", filepath.Join(root, "missing", "report.json")}, exitBuildFailed},
+	}
+	for _, step := range steps {
+		step.change()
+		if code := run(step.args); code != step.want {
+			t.Errorf("%s: код %d, ожидался %d", step.name, code, step.want)
+		}
+	}
+
+	// Последний записанный отчёт — сборка с -fail-fast
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report BuildReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Aborted || len(report.Errors) == 0 || report.Errors[0].Item != "b" {
+		t.Errorf("отчёт -fail-fast: %s", data)
+	}
+}
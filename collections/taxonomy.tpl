@@ -0,0 +1,36 @@
+<!DOCTYPE html>
+	<html lang="ru">
+	<head>
+		<meta charset="UTF-8">
+		<title>{term}{if page.prev} — страница {page.number}{end}</title>
+		<style>
+			table { border-collapse: collapse; width: 100%; }
+			th, td { border: 1px solid #ccc; padding: 8px; text-align: left; }
+			.category { margin-bottom: 20px; }
+			h2 { margin-top: 30px; }
+			.pagination { margin: 20px 0; text-align: center; }
+			.pagination a, .pagination span { margin: 0 4px; padding: 5px 10px; }
+		</style>
+	</head>
+	<body>
+		{header}
+		<h1><a href="/{taxonomy}/">{taxonomy}</a></h1>
+		<div class="category">
+			<h2>{term}</h2>
+			<table>
+				<thead><tr><th>Заголовок</th><th>Ссылка</th></tr></thead>
+				<tbody>
+				{each items}
+					<tr><td>{title}</td><td><a href="{url}">{url}</a></td></tr>
+				{end}
+				</tbody>
+			</table>
+		</div>
+		<nav class="pagination">
+			{if page.prev}<a href="{page.first}">« Первая</a><a href="{page.prev}" rel="prev">‹ Назад</a>{end}
+			<span>Страница {page.number} из {page.total}</span>
+			{if page.next}<a href="{page.next}" rel="next">Вперёд ›</a><a href="{page.last}">Последняя »</a>{end}
+		</nav>
+		{footer}
+	</body>
+	</html>
@@ -0,0 +1,24 @@
+<!DOCTYPE html>
+	<html lang="ru">
+	<head>
+		<meta charset="UTF-8">
+		<title>{taxonomy}</title>
+		<style>
+			ul.terms { list-style: none; padding: 0; }
+			ul.terms li { margin: 6px 0; }
+			.count { color: #888; }
+		</style>
+	</head>
+	<body>
+		{header}
+		<h1>{taxonomy}</h1>
+		<ul class="terms">
+		{each terms}
+			<li><a href="{url}">{name}</a> <span class="count">({count})</span></li>
+		{else}
+			<li>Нет терминов</li>
+		{end}
+		</ul>
+		{footer}
+	</body>
+	</html>
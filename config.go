@@ -0,0 +1,297 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Файл конфигурации сайта goferret.json / goferret.toml.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Имена файлов конфигурации, которые ищутся в корне сайта
+const (
+	configFileJSON = "goferret.json"
+	configFileTOML = "goferret.toml"
+)
+
+// SiteConfig описывает файл конфигурации проекта
+type SiteConfig struct {
+	// Site содержит глобальные переменные сайта, доступные шаблонам как {site.<ключ>}
+	Site map[string]interface{} `json:"site"`
+	// Build содержит настройки сборки
+	Build BuildConfig `json:"build"`
+	// Taxonomies задаёт группировки страниц помимо category: теги, авторов и другие
+	Taxonomies map[string]TaxonomyConfig `json:"taxonomies"`
+	// Feeds содержит настройки лент RSS и Atom
+	Feeds FeedConfig `json:"feeds"`
+	// Sitemap содержит настройки карты сайта и robots.txt
+	Sitemap SitemapConfig `json:"sitemap"`
+	// Assets содержит настройки файлов static/
+	Assets AssetConfig `json:"assets"`
+	// Minify содержит настройки минификации HTML, CSS и JavaScript
+	Minify MinifyConfig `json:"minify"`
+}
+
+// MinifyConfig описывает минификацию страниц, списков терминов и копируемых файлов CSS и JavaScript
+type MinifyConfig struct {
+	Enabled bool     `json:"enabled"` // Минифицировать результат сборки
+	Exclude []string `json:"exclude"` // Шаблоны, результат которых записывается без минификации
+}
+
+// AssetConfig описывает отпечатки содержимого в именах файлов static/ и хэши SRI
+type AssetConfig struct {
+	Fingerprint []string `json:"fingerprint"` // Шаблоны путей файлов, в имя которых добавляется отпечаток: "*.css", "js/*.js"
+	Integrity   string   `json:"integrity"`   // Алгоритм хэша для {integrity}: sha256, sha384 или sha512
+	Manifest    string   `json:"manifest"`    // JSON с итоговыми путями файлов с отпечатком
+}
+
+// SitemapConfig описывает sitemap.xml и robots.txt. Как и ленты, они пишутся, только если задан site.baseURL.
+type SitemapConfig struct {
+	Enabled bool `json:"enabled"` // Записывать sitemap.xml
+	Robots  bool `json:"robots"`  // Записывать robots.txt со ссылкой на карту сайта
+}
+
+// FeedConfig описывает ленты сайта и терминов. Ленты генерируются, только если задан site.baseURL.
+type FeedConfig struct {
+	Formats []string `json:"formats"` // rss и/или atom; пустой список отключает ленты
+	Limit   int      `json:"limit"`   // Записей в одной ленте
+	Title   string   `json:"title"`   // Атрибут заголовка записи
+	Summary string   `json:"summary"` // Атрибут описания записи
+	Date    string   `json:"date"`    // Атрибут даты записи, по нему же записи упорядочены
+}
+
+// TaxonomyConfig описывает одну таксономию
+type TaxonomyConfig struct {
+	Key       string   `json:"key"`       // Атрибут страницы со списком терминов; по умолчанию имя таксономии
+	SortBy    string   `json:"sortBy"`    // Порядок элементов; по умолчанию build.sortBy
+	SortOrder string   `json:"sortOrder"` // По умолчанию build.sortOrder
+	Fields    []string `json:"fields"`    // Поля элементов; по умолчанию build.itemFields
+}
+
+// BuildConfig содержит настройки сборки: директорию вывода, шаблон по умолчанию, вид адресов,
+// размер страниц и порядок элементов списков и число воркеров
+type BuildConfig struct {
+	Output          string   `json:"output"`
+	DefaultTemplate string   `json:"defaultTemplate"`
+	PrettyURLs      bool     `json:"prettyURLs"`
+	PageSize        int      `json:"pageSize"`     // Элементов на одной странице списка термина
+	CategoryJSON    bool     `json:"categoryJSON"` // Записывать JSON со всеми элементами термина (по умолчанию да)
+	SortBy          string   `json:"sortBy"`       // Ключ сортировки элементов списков: id, title, date, weight или атрибут
+	SortOrder       string   `json:"sortOrder"`    // asc или desc
+	ItemFields      []string `json:"itemFields"`   // Дополнительные поля элементов списков: "summary", "date:date", "readingTime"
+	MarkdownHTML    bool     `json:"markdownHTML"` // Выводить HTML из Markdown как есть; по умолчанию он экранируется
+	Readers         int      `json:"readers"`
+	Processors      int      `json:"processors"`
+	Writers         int      `json:"writers"`
+	CategoryWorkers int      `json:"categoryWorkers"`
+}
+
+// defaultConfig возвращает конфигурацию, используемую при отсутствии файла
+func defaultConfig() *SiteConfig {
+	return &SiteConfig{
+		Site: make(map[string]interface{}),
+		Build: BuildConfig{
+			PageSize:        20,
+			CategoryJSON:    true,
+			Readers:         200,
+			Processors:      2,
+			Writers:         200,
+			CategoryWorkers: 4,
+		},
+		Feeds: FeedConfig{
+			Formats: []string{feedRSS, feedAtom},
+			Limit:   20,
+			Title:   "title",
+			Summary: "summary",
+			Date:    "date",
+		},
+		Sitemap: SitemapConfig{Enabled: true, Robots: true},
+		Assets:  AssetConfig{Integrity: "sha384", Manifest: "assets.json"},
+	}
+}
+
+// findConfigFile ищет goferret.json или goferret.toml в корне сайта.
+// Возвращает пустую строку, если файла нет, и ошибку, если найдены оба.
+func findConfigFile(root string) (string, error) {
+	var found []string
+	for _, name := range []string{configFileJSON, configFileTOML} {
+		path := filepath.Join(root, name)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("найдено несколько файлов конфигурации: %s", strings.Join(found, ", "))
+	}
+}
+
+// loadConfig читает файл конфигурации по пути path. Пустой путь означает конфигурацию по умолчанию.
+func loadConfig(path string) (*SiteConfig, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении конфигурации %s: %v", path, err)
+	}
+
+	jsonData := data
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		table, err := parseTOML(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка в файле %s: %v", path, err)
+		}
+		// Приводим TOML к JSON, чтобы оба формата заполняли одну и ту же структуру
+		if jsonData, err = json.Marshal(table); err != nil {
+			return nil, fmt.Errorf("ошибка в файле %s: %v", path, err)
+		}
+	}
+
+	if err := json.Unmarshal(jsonData, cfg); err != nil {
+		return nil, fmt.Errorf("ошибка в файле %s: %v", path, err)
+	}
+	if cfg.Site == nil {
+		cfg.Site = make(map[string]interface{})
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("ошибка в файле %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// validate проверяет настройки сборки
+func (cfg *SiteConfig) validate() error {
+	positive := map[string]int{
+		"build.readers":         cfg.Build.Readers,
+		"build.processors":      cfg.Build.Processors,
+		"build.writers":         cfg.Build.Writers,
+		"build.categoryWorkers": cfg.Build.CategoryWorkers,
+		"build.pageSize":        cfg.Build.PageSize,
+		"feeds.limit":           cfg.Feeds.Limit,
+	}
+	for name, n := range positive {
+		if n < 1 {
+			return fmt.Errorf("%s должно быть положительным числом, получено %d", name, n)
+		}
+	}
+	if err := validateSortOrder("build.sortOrder", cfg.Build.SortOrder); err != nil {
+		return err
+	}
+	if _, err := parseItemFields(cfg.Build.ItemFields); err != nil {
+		return fmt.Errorf("build.itemFields: %v", err)
+	}
+	for _, format := range cfg.Feeds.Formats {
+		if format != feedRSS && format != feedAtom {
+			return fmt.Errorf("feeds.formats: неизвестный формат %q, допускаются rss и atom", format)
+		}
+	}
+	if err := validateBaseURL(cfg.Site["baseURL"]); err != nil {
+		return err
+	}
+	for _, pattern := range cfg.Assets.Fingerprint {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("assets.fingerprint: недопустимый шаблон %q", pattern)
+		}
+	}
+	if _, ok := integrityHashes[cfg.Assets.Integrity]; !ok {
+		return fmt.Errorf("assets.integrity должно быть sha256, sha384 или sha512, получено %q", cfg.Assets.Integrity)
+	}
+	if len(cfg.Assets.Fingerprint) > 0 && !isAssetName(cfg.Assets.Manifest) {
+		return fmt.Errorf("assets.manifest: недопустимый путь %q", cfg.Assets.Manifest)
+	}
+	for _, name := range cfg.Minify.Exclude {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("minify.exclude: пустое имя шаблона")
+		}
+	}
+	for name, tax := range cfg.Taxonomies {
+		if err := validateSortOrder("taxonomies."+name+".sortOrder", tax.SortOrder); err != nil {
+			return err
+		}
+		if _, err := parseItemFields(tax.Fields); err != nil {
+			return fmt.Errorf("taxonomies.%s.fields: %v", name, err)
+		}
+		// Имя таксономии становится директорией сборки
+		if name == categoryTaxonomy {
+			return fmt.Errorf("таксономия %s встроена и задаётся файлами category.val", name)
+		}
+		if name == "" || slugify(name) != name {
+			return fmt.Errorf("недопустимое имя таксономии %q: допускаются строчные буквы, цифры и дефис", name)
+		}
+	}
+	return nil
+}
+
+// validateSortOrder проверяет направление сортировки
+func validateSortOrder(name, order string) error {
+	switch order {
+	case "", "asc", "desc":
+		return nil
+	}
+	return fmt.Errorf("%s должно быть asc или desc, получено %q", name, order)
+}
+
+// templateVars превращает секцию site в плоский набор переменных {site.<ключ>}.
+// Вложенные таблицы дают составные ключи ({site.social.github}), массивы склеиваются через запятую.
+func (cfg *SiteConfig) templateVars() map[string]string {
+	vars := make(map[string]string)
+	flattenConfigValue("site", cfg.Site, vars)
+	return vars
+}
+
+// flattenConfigValue записывает значение конфигурации в vars под ключом prefix
+func flattenConfigValue(prefix string, value interface{}, vars map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flattenConfigValue(prefix+"."+k, v[k], vars)
+		}
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for i, item := range v {
+			flattenConfigValue(fmt.Sprintf("%s.%d", prefix, i), item, vars)
+			parts = append(parts, formatConfigScalar(item))
+		}
+		vars[prefix] = strings.Join(parts, ", ")
+	default:
+		vars[prefix] = formatConfigScalar(v)
+	}
+}
+
+// formatConfigScalar форматирует скалярное значение конфигурации для подстановки в шаблон
+func formatConfigScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
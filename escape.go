@@ -0,0 +1,484 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Контекстное экранирование подстановок: текст HTML, атрибуты, адреса и JavaScript.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Состояния разбора HTML, в которых может оказаться подстановка
+const (
+	escText        = iota // Текст HTML
+	escTag                // Внутри тега между атрибутами
+	escBeforeValue        // После "=" перед значением атрибута
+	escAttr               // Значение атрибута
+	escScript             // Код внутри <script>
+	escStyle              // Таблица стилей внутри <style>
+	escComment            // Комментарий HTML
+)
+
+// Виды атрибутов
+const (
+	attrPlain = iota
+	attrURL   // href, src и другие атрибуты с адресом
+	attrJS    // Обработчики событий on*
+	attrCSS   // Атрибут style
+)
+
+// Состояния разбора JavaScript внутри <script> и атрибутов on*
+const (
+	jsCode         = iota // Код вне литералов и комментариев
+	jsQuoted              // Строковый литерал в кавычках '', "" или ``
+	jsLineComment         // Комментарий //
+	jsBlockComment        // Комментарий /* */
+	jsRegexp              // Литерал регулярного выражения
+	jsRegexpClass         // Класс символов [...] внутри регулярного выражения
+	jsUnknown             // Разбор невозможен, например внутри ${...} шаблонной строки
+)
+
+// regexpKeywords — слова, после которых "/" начинает регулярное выражение, а не деление
+var regexpKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+// Части адреса в значении атрибута
+const (
+	urlStart = iota
+	urlPath
+	urlQuery
+)
+
+// Элементы, содержимое которых не является HTML
+const (
+	elemNone = iota
+	elemScript
+	elemStyle
+)
+
+// escUnsafe подставляется вместо значения, которое нельзя безопасно вывести в данном месте
+const escUnsafe = "unsafe"
+
+// escContext описывает место в выводе, куда попадёт следующая подстановка
+type escContext struct {
+	state   uint8
+	quote   byte // Кавычка значения атрибута; 0 — значение без кавычек
+	attr    uint8
+	urlPart uint8
+	element uint8 // Элемент, тег которого сейчас разбирается
+	js      uint8 // Состояние разбора JavaScript в <script> и атрибутах on*
+	jsQuote byte  // Кавычка строкового литерала JavaScript
+	jsDiv   bool  // "/" в коде JavaScript означает деление, а не начало регулярного выражения
+	jsEsc   bool  // Предыдущий символ строки или регулярного выражения — "\"
+}
+
+// urlAttrs — атрибуты, значения которых являются адресами
+var urlAttrs = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "cite": true,
+	"poster": true, "background": true, "longdesc": true, "usemap": true, "manifest": true,
+	"srcset": true, "ping": true, "xlink:href": true,
+}
+
+// advance возвращает контекст после вывода s
+func (c escContext) advance(s string) escContext {
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch c.state {
+		case escText:
+			if ch != '<' {
+				continue
+			}
+			if strings.HasPrefix(s[i+1:], "!--") {
+				c.state = escComment
+				i += 3
+				continue
+			}
+			rest := s[i+1:]
+			closing := strings.HasPrefix(rest, "/")
+			if closing {
+				rest = rest[1:]
+			}
+			n := tagNameLen(rest)
+			if n == 0 {
+				continue
+			}
+			c = escContext{state: escTag}
+			if !closing {
+				switch strings.ToLower(rest[:n]) {
+				case "script":
+					c.element = elemScript
+				case "style":
+					c.element = elemStyle
+				}
+			}
+			i += n
+			if closing {
+				i++
+			}
+		case escComment:
+			if strings.HasPrefix(s[i:], "-->") {
+				c.state = escText
+				i += 2
+			}
+		case escTag:
+			switch {
+			case ch == '>':
+				c = c.closeTag()
+			case ch == '=':
+				c.state = escBeforeValue
+			case isHTMLSpace(ch) || ch == '/':
+			default:
+				n := attrNameLen(s[i:])
+				c.attr = classifyAttr(strings.ToLower(s[i : i+n]))
+				i += n - 1
+			}
+		case escBeforeValue:
+			switch {
+			case isHTMLSpace(ch):
+			case ch == '>':
+				c = c.closeTag()
+			case ch == '"' || ch == '\'':
+				c = escContext{state: escAttr, quote: ch, attr: c.attr, element: c.element}
+			default:
+				c = escContext{state: escAttr, attr: c.attr, element: c.element}
+				i--
+			}
+		case escAttr:
+			switch {
+			case c.quote != 0 && ch == c.quote, c.quote == 0 && isHTMLSpace(ch):
+				c = escContext{state: escTag, element: c.element}
+			case c.quote == 0 && ch == '>':
+				c = c.closeTag()
+			case c.attr == attrJS:
+				// Браузер декодирует ссылки на символы до того, как значение разберёт JavaScript
+				decoded, n := decodeCharRef(s[i:])
+				var next byte
+				if i+n < len(s) {
+					next = s[i+n]
+				}
+				var skip bool
+				c, skip = c.advanceJS(decoded, next, s[:i])
+				i += n - 1
+				if skip {
+					i++
+				}
+			case c.attr == attrURL && (ch == '?' || ch == '#'):
+				c.urlPart = urlQuery
+			case c.attr == attrURL && c.urlPart == urlStart:
+				c.urlPart = urlPath
+			}
+		case escScript:
+			// </script> закрывает элемент в любом месте кода, даже внутри строки или комментария
+			if ch == '<' && hasPrefixFold(s[i:], "</script") {
+				c = escContext{state: escTag}
+				i += len("</script") - 1
+				continue
+			}
+			var next byte
+			if i+1 < len(s) {
+				next = s[i+1]
+			}
+			var skip bool
+			if c, skip = c.advanceJS(ch, next, s[:i]); skip {
+				i++
+			}
+		case escStyle:
+			if ch == '<' && hasPrefixFold(s[i:], "</style") {
+				c = escContext{state: escTag}
+				i += len("</style") - 1
+			}
+		}
+	}
+	return c
+}
+
+// advanceJS возвращает контекст после символа ch кода JavaScript. next — следующий символ
+// (0 в конце текста), before — уже разобранный текст для поиска слова перед "/".
+// Второй результат сообщает, что next тоже разобран как часть "//", "/*" или "*/".
+func (c escContext) advanceJS(ch, next byte, before string) (escContext, bool) {
+	if c.jsEsc {
+		c.jsEsc = false
+		return c, false
+	}
+	switch c.js {
+	case jsCode:
+		switch {
+		case ch == '"' || ch == '\'' || ch == '`':
+			c.js, c.jsQuote = jsQuoted, ch
+		case ch == '/' && next == '/':
+			c.js = jsLineComment
+			return c, true
+		case ch == '/' && next == '*':
+			c.js = jsBlockComment
+			return c, true
+		case ch == '/':
+			if c.jsDiv && !regexpKeywords[lastWord(before)] {
+				c.jsDiv = false
+			} else {
+				c.js = jsRegexp
+			}
+		case isHTMLSpace(ch):
+		default:
+			c.jsDiv = isJSIdentChar(ch) || ch == ')' || ch == ']'
+		}
+	case jsQuoted:
+		switch {
+		case ch == '\\':
+			c.jsEsc = true
+		case ch == c.jsQuote:
+			c.js, c.jsQuote, c.jsDiv = jsCode, 0, true
+		case c.jsQuote == '`' && ch == '$' && next == '{':
+			c.js = jsUnknown
+		}
+	case jsLineComment:
+		if ch == '\n' || ch == '\r' {
+			c.js = jsCode
+		}
+	case jsBlockComment:
+		if ch == '*' && next == '/' {
+			c.js = jsCode
+			return c, true
+		}
+	case jsRegexp, jsRegexpClass:
+		switch {
+		case ch == '\\':
+			c.jsEsc = true
+		case ch == '[':
+			c.js = jsRegexpClass
+		case ch == ']' && c.js == jsRegexpClass:
+			c.js = jsRegexp
+		case ch == '/' && c.js == jsRegexp:
+			// Флаги после литерала — буквы, после них "/" тоже означает деление
+			c.js, c.jsDiv = jsCode, true
+		}
+	}
+	return c, false
+}
+
+// lastWord возвращает слово из букв, цифр, "_" и "$" в конце s без учёта пробелов
+func lastWord(s string) string {
+	s = strings.TrimRight(s, " \t\n\r\f")
+	i := len(s)
+	for i > 0 && isJSIdentChar(s[i-1]) {
+		i--
+	}
+	return s[i:]
+}
+
+func isJSIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$'
+}
+
+// decodeCharRef декодирует ссылку на символ (&#39;, &quot;) в начале s.
+// Возвращает символ и число разобранных байтов; для прочего текста — первый байт.
+func decodeCharRef(s string) (byte, int) {
+	if s[0] == '&' {
+		if end := strings.IndexByte(s, ';'); end > 1 && end <= 10 {
+			if decoded := html.UnescapeString(s[:end+1]); len(decoded) == 1 {
+				return decoded[0], end + 1
+			}
+		}
+	}
+	return s[0], 1
+}
+
+// escapeJS экранирует значение для кода JavaScript: в коде оно выводится строковым литералом,
+// внутри строки — её содержимым. В комментарии, регулярном выражении и после "\\" безопасного
+// представления нет.
+func (c escContext) escapeJS(s string) string {
+	switch {
+	case c.jsEsc:
+		return escUnsafe
+	case c.js == jsCode:
+		return jsValue(s)
+	case c.js == jsQuoted:
+		return jsString(s)
+	}
+	return escUnsafe
+}
+
+// closeTag возвращает контекст после ">": содержимое <script> и <style> разбирается отдельно
+func (c escContext) closeTag() escContext {
+	switch c.element {
+	case elemScript:
+		return escContext{state: escScript}
+	case elemStyle:
+		return escContext{state: escStyle}
+	}
+	return escContext{}
+}
+
+// escape экранирует значение s для вывода в контексте c
+func (c escContext) escape(s string) string {
+	switch c.state {
+	case escTag:
+		// Значение между атрибутами может добавить тегу новый атрибут, например обработчик события
+		return escUnsafe
+	case escScript:
+		return c.escapeJS(s)
+	case escStyle:
+		return cssValue(s)
+	case escBeforeValue:
+		return escContext{state: escAttr, attr: c.attr, element: c.element}.escape(s)
+	case escAttr:
+		switch c.attr {
+		case attrURL:
+			s = escapeURL(s, c.urlPart)
+		case attrJS:
+			s = c.escapeJS(s)
+		case attrCSS:
+			s = cssValue(s)
+		}
+		if c.quote == 0 {
+			return escapeUnquoted(s)
+		}
+	}
+	return html.EscapeString(s)
+}
+
+// classifyAttr определяет вид атрибута по имени
+func classifyAttr(name string) uint8 {
+	switch {
+	case urlAttrs[name]:
+		return attrURL
+	case strings.HasPrefix(name, "on"):
+		return attrJS
+	case name == "style":
+		return attrCSS
+	}
+	return attrPlain
+}
+
+// escapeURL готовит значение для адреса. В начале адреса допускаются только безопасные схемы,
+// в пути сохраняются служебные символы, а в строке запроса значение кодируется целиком.
+func escapeURL(s string, part uint8) string {
+	switch part {
+	case urlQuery:
+		return url.QueryEscape(s)
+	case urlStart:
+		if !safeURLScheme(s) {
+			return "#" + escUnsafe
+		}
+	}
+	return normalizeURL(s)
+}
+
+// safeURLScheme разрешает относительные адреса и схемы http, https, mailto, tel и ftp
+func safeURLScheme(s string) bool {
+	i := strings.IndexAny(s, ":/?#")
+	if i < 0 || s[i] != ':' {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(s[:i])) {
+	case "http", "https", "mailto", "tel", "ftp":
+		return true
+	}
+	return false
+}
+
+// normalizeURL кодирует байты, недопустимые в адресе, сохраняя уже закодированные последовательности
+func normalizeURL(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("-_.~!*'();:@&=+$,/?#[]%", c) >= 0 {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&15])
+	}
+	return sb.String()
+}
+
+// jsValue выводит значение как строковый литерал JavaScript
+func jsValue(s string) string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return `""`
+	}
+	return string(data)
+}
+
+// jsString экранирует значение внутри строкового литерала JavaScript с любыми кавычками.
+// Знак "$" экранируется, чтобы в шаблонной строке `...` значение не стало выражением ${...}.
+func jsString(s string) string {
+	quoted := jsValue(s)
+	quoted = quoted[1 : len(quoted)-1]
+	return strings.NewReplacer("'", `\u0027`, "`", `\u0060`, "$", `\u0024`).Replace(quoted)
+}
+
+// cssValue пропускает в таблицу стилей только значения без символов, меняющих её разбор,
+// и без адресов url(...) с опасной схемой
+func cssValue(s string) string {
+	lower := strings.ToLower(s)
+	if strings.ContainsAny(s, "<>\"'{};\\@") || strings.Contains(lower, "expression") {
+		return escUnsafe
+	}
+	for rest := lower; ; {
+		i := strings.Index(rest, "url(")
+		if i < 0 {
+			break
+		}
+		rest = rest[i+len("url("):]
+		arg := rest
+		if j := strings.IndexByte(arg, ')'); j >= 0 {
+			arg = arg[:j]
+		}
+		if !safeURLScheme(strings.TrimSpace(arg)) {
+			return escUnsafe
+		}
+	}
+	return s
+}
+
+// escapeUnquoted экранирует значение атрибута без кавычек, включая пробелы и знак "="
+func escapeUnquoted(s string) string {
+	return strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;",
+		" ", "&#32;", "\t", "&#9;", "\n", "&#10;", "=", "&#61;", "`", "&#96;",
+	).Replace(s)
+}
+
+// tagNameLen возвращает длину имени тега в начале s
+func tagNameLen(s string) int {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || n > 0 && (c >= '0' && c <= '9' || c == '-')) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// attrNameLen возвращает длину имени атрибута в начале s (не меньше одного байта)
+func attrNameLen(s string) int {
+	n := 1
+	for n < len(s) && !isHTMLSpace(s[n]) && strings.IndexByte("=>/\"'", s[n]) < 0 {
+		n++
+	}
+	return n
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// hasPrefixFold сообщает, начинается ли s с prefix без учёта регистра
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
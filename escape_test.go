@@ -0,0 +1,142 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты контекстного экранирования подстановок.
+*/
+
+package main
+
+import "testing"
+
+func TestContextEscaping(t *testing.T) {
+	tests := []struct {
+		name  string
+		tpl   string
+		value string
+		want  string
+	}{
+		{"текст", "<p>{v}</p>", `<b>"x" & 'y'</b>`, "<p>&lt;b&gt;&#34;x&#34; &amp; &#39;y&#39;&lt;/b&gt;</p>"},
+		{"атрибут в двойных кавычках", `<a title="{v}">`, `a" onclick="x`, `<a title="a&#34; onclick=&#34;x">`},
+		{"атрибут в одинарных кавычках", `<a title='{v}'>`, `a' b`, `<a title='a&#39; b'>`},
+		{"атрибут без кавычек", `<a title={v}>`, `a b=c`, `<a title=a&#32;b&#61;c>`},
+		{"опасная схема", `<a href="{v}">`, `javascript:alert(1)`, `<a href="#unsafe">`},
+		{"опасная схема с пробелами", `<a href="{v}">`, ` JavaScript:alert(1)`, `<a href="#unsafe">`},
+		{"адрес", `<a href="{v}">`, `https://x.com/a b?c=d`, `<a href="https://x.com/a%20b?c=d">`},
+		{"строка запроса", `<a href="/search?q={v}">`, `a&b c`, `<a href="/search?q=a%26b+c">`},
+		{"путь", `<a href="/p/{v}">`, `a b/"c`, `<a href="/p/a%20b/%22c">`},
+		{"адрес без кавычек", `<img src={v}>`, `x onerror=alert(1)`, `<img src=x%20onerror&#61;alert(1)>`},
+		{"обработчик события", `<a onclick="f({v})">`, `"); alert(1); ("`, `<a onclick="f(&#34;\&#34;); alert(1); (\&#34;&#34;)">`},
+		{"скрипт", "<script>var x = {v};</script>", `</script><script>alert(1)`,
+			`<script>var x = "\u003c/script\u003e\u003cscript\u003ealert(1)";</script>`},
+		{"строка в скрипте", "<script>var x = '{v}';</script>", `' + alert(1) + '`, `<script>var x = '\u0027 + alert(1) + \u0027';</script>`},
+		{"после скрипта снова текст", "<script>x</script><p>{v}</p>", `<i>`, "<script>x</script><p>&lt;i&gt;</p>"},
+		{"стиль", "<style>p { color: {v}; }</style>", `red`, "<style>p { color: red; }</style>"},
+		{"опасный стиль", "<style>p { color: {v}; }</style>", `red; } body { x: y`, "<style>p { color: unsafe; }</style>"},
+		{"комментарий", "<!-- {v} -->", `--> <script>`, "<!-- --&gt; &lt;script&gt; -->"},
+		{"фильтр raw", "<p>{v|raw}</p>", `<b>x</b>`, "<p><b>x</b></p>"},
+		{"внутри тега", "<input {v}>", `x onfocus=alert(1) autofocus`, "<input unsafe>"},
+		{"внутри тега после атрибута", `<a href="/" {v}>`, `onclick=alert(1)`, `<a href="/" unsafe>`},
+		{"шаблонная строка в скрипте", "<script>var s = `{v}`;</script>", `${alert(1)}`,
+			"<script>var s = `\\u0024{alert(1)}`;</script>"},
+		{"атрибут style", `<p style="color: {v}">`, `red`, `<p style="color: red">`},
+		{"атрибут style с url", `<p style="{v}">`, `background:url(javascript:alert(1))`, `<p style="unsafe">`},
+		{"атрибут style с expression", `<p style="width: {v}">`, `expression(alert(1))`, `<p style="width: unsafe">`},
+		{"безопасный url в style", `<p style="{v}">`, `background:url(/img/a.png)`, `<p style="background:url(/img/a.png)">`},
+		{"опасный url в таблице стилей", "<style>p { background: {v}; }</style>", `url(javascript:x)`,
+			"<style>p { background: unsafe; }</style>"},
+		{"srcset", `<img srcset="{v}">`, `javascript:alert(1)`, `<img srcset="#unsafe">`},
+		{"ping", `<a ping="{v}">`, `javascript:alert(1)`, `<a ping="#unsafe">`},
+		{"xlink:href", `<use xlink:href="{v}">`, `javascript:alert(1)`, `<use xlink:href="#unsafe">`},
+		{"строка в обработчике события", `<button onclick="go('{v}')">`, `');alert(1);//`,
+			`<button onclick="go('\u0027);alert(1);//')">`},
+		{"строка со ссылкой на символ в обработчике", `<button onclick="go(&#39;{v}&#39;)">`, `');alert(1);//`,
+			`<button onclick="go(&#39;\u0027);alert(1);//&#39;)">`},
+		{"после строки в обработчике", `<button onclick="go('a', {v})">`, `x`, `<button onclick="go('a', &#34;x&#34;)">`},
+		{"обработчик в одинарных кавычках", `<a onclick='f("{v}")'>`, `"+alert(1)+"`, `<a onclick='f("\&#34;+alert(1)+\&#34;")'>`},
+		{"апостроф в строчном комментарии", "<script>// don't\nvar x = {v};</script>", `1`, "<script>// don't\nvar x = \"1\";</script>"},
+		{"кавычка в блочном комментарии", "<script>/* it's */ var x = {v};</script>", `1`, "<script>/* it's */ var x = \"1\";</script>"},
+		{"кавычка в регулярном выражении", "<script>var r = /'/; var x = {v};</script>", `1`, "<script>var r = /'/; var x = \"1\";</script>"},
+		{"кавычка в классе регулярного выражения", "<script>var r = /[/']/g, x = {v};</script>", `1`, "<script>var r = /[/']/g, x = \"1\";</script>"},
+		{"регулярное выражение после return", "<script>function f() { return /'/.test({v}); }</script>", `1`,
+			"<script>function f() { return /'/.test(\"1\"); }</script>"},
+		{"деление", "<script>var x = a / 2, y = '{v}';</script>", `'`, "<script>var x = a / 2, y = '\\u0027';</script>"},
+		{"внутри комментария в скрипте", "<script>// {v}\n</script>", "\nalert(1)", "<script>// unsafe\n</script>"},
+		{"внутри регулярного выражения", "<script>var r = /{v}/;</script>", `/;alert(1);/`, "<script>var r = /unsafe/;</script>"},
+		{"выражение в шаблонной строке", "<script>var s = `${a + {v}}`;</script>", `1`, "<script>var s = `${a + unsafe}`;</script>"},
+		{"после обратной косой черты", `<script>var s = "\{v}";</script>`, `x`, `<script>var s = "\unsafe";</script>`},
+		{"комментарий в обработчике события", `<a onclick="f() // {v}">`, `x`, `<a onclick="f() // unsafe">`},
+	}
+	for _, tt := range tests {
+		tpl, err := compileTemplate("test", tt.tpl)
+		if err != nil {
+			t.Errorf("%s: ошибка разбора %v", tt.name, err)
+			continue
+		}
+		got, err := tpl.Execute(map[string]string{"v": tt.value}, nil, nil)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\nполучено  %s\nожидалось %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSafeValueSkipsEscaping(t *testing.T) {
+	tpl, err := compileTemplate("test", "<div>{content}</div>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tpl.Execute(map[string]string{"content": "<p>x</p>"}, map[string]bool{"content": true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<div><p>x</p></div>"; got != want {
+		t.Errorf("получено %s, ожидалось %s", got, want)
+	}
+}
+
+func TestSafeURLScheme(t *testing.T) {
+	tests := []struct {
+		url  string
+		safe bool
+	}{
+		{"/page", true},
+		{"page.html", true},
+		{"#anchor", true},
+		{"?q=a:b", true},
+		{"/p:x", true},
+		{"http://x.com", true},
+		{"HTTPS://x.com", true},
+		{"mailto:a@b.c", true},
+		{"tel:+100", true},
+		{"javascript:alert(1)", false},
+		{"JAVASCRIPT:x", false},
+		{" javascript:x", false},
+		{"java\tscript:x", false},
+		{"data:text/html,x", false},
+		{"vbscript:x", false},
+	}
+	for _, tt := range tests {
+		if got := safeURLScheme(tt.url); got != tt.safe {
+			t.Errorf("safeURLScheme(%q) = %v, ожидалось %v", tt.url, got, tt.safe)
+		}
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/a b", "/a%20b"},
+		{"/a%20b", "/a%20b"},
+		{"/путь", "/%D0%BF%D1%83%D1%82%D1%8C"},
+		{`/a"<b>`, "/a%22%3Cb%3E"},
+		{"/a?b=c&d=e#f", "/a?b=c&d=e#f"},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.in); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
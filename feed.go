@@ -0,0 +1,329 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Ленты RSS 2.0 и Atom 1.0 для сайта и терминов таксономий.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Форматы лент и имена их файлов
+const (
+	feedRSS      = "rss"
+	feedAtom     = "atom"
+	feedRSSFile  = "rss.xml"
+	feedAtomFile = "atom.xml"
+)
+
+// siteFeedKey — ключ ленты всего сайта в манифесте сборки, не совпадающий с ключами таксономий
+const siteFeedKey = "/"
+
+// feedEntry — запись ленты, подготовленная по странице
+type feedEntry struct {
+	Title   string    `json:"title"`
+	Summary string    `json:"summary"`
+	HTML    bool      `json:"html"` // Описание — доверенный HTML, например из Markdown
+	URL     string    `json:"url"`  // Абсолютный адрес страницы
+	Date    time.Time `json:"date"` // Нулевое время, если даты нет
+}
+
+// siteFeed — лента сайта или термина
+type siteFeed struct {
+	Title   string      `json:"title"`
+	Link    string      `json:"link"` // Абсолютный адрес страницы, которую описывает лента
+	Dir     string      `json:"dir"`  // Директория файлов ленты относительно сборки; пустая — корень
+	Entries []feedEntry `json:"entries"`
+}
+
+// siteBaseURL возвращает site.baseURL без завершающего "/". Без него ленты не генерируются,
+// потому что ссылки в них должны быть абсолютными.
+func siteBaseURL(cfg *SiteConfig) string {
+	base, _ := cfg.Site["baseURL"].(string)
+	return strings.TrimRight(strings.TrimSpace(base), "/")
+}
+
+// absoluteURL соединяет base (site.baseURL без "/" в конце) с адресом страницы на сайте.
+// Путь кодируется, поэтому адреса с пробелами и кириллицей остаются корректными URL.
+func absoluteURL(base, sitePath string) string {
+	return base + (&url.URL{Path: sitePath}).EscapedPath()
+}
+
+// feedsEnabled сообщает, нужно ли генерировать ленты
+func feedsEnabled(cfg *SiteConfig) bool {
+	return len(cfg.Feeds.Formats) > 0 && siteBaseURL(cfg) != ""
+}
+
+// validateBaseURL проверяет, что site.baseURL — абсолютный адрес http или https
+func validateBaseURL(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	base, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("site.baseURL должен быть строкой")
+	}
+	if base == "" {
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("site.baseURL должен быть абсолютным адресом http или https, получено %q", base)
+	}
+	return nil
+}
+
+// newSiteFeed собирает ленту из страниц: самые новые по атрибуту feeds.date первыми,
+// не больше feeds.limit записей. Страницы без даты идут последними.
+func newSiteFeed(title, link, dir string, models []*Model, cfg *SiteConfig) *siteFeed {
+	fc := cfg.Feeds
+	base := siteBaseURL(cfg)
+	sorted := sortModels(models, fc.Date, true)
+	if len(sorted) > fc.Limit {
+		sorted = sorted[:fc.Limit]
+	}
+
+	feed := &siteFeed{Title: title, Link: link, Dir: dir, Entries: make([]feedEntry, 0, len(sorted))}
+	for _, model := range sorted {
+		entry := feedEntry{URL: absoluteURL(base, model.URL)}
+		titleValue, _ := lookupFlat(model.Data, fc.Title)
+		if entry.Title = strings.TrimSpace(formatValue(titleValue)); entry.Title == "" {
+			entry.Title = model.ID
+		}
+		summaryValue, _ := lookupFlat(model.Data, fc.Summary)
+		entry.Summary = strings.TrimSpace(formatValue(summaryValue))
+		entry.HTML = model.Safe[fc.Summary]
+		if t, ok := model.Dates[fc.Date]; ok {
+			entry.Date = t
+		} else if dateValue, _ := lookupFlat(model.Data, fc.Date); dateValue != nil {
+			entry.Date, _ = parseDate(strings.TrimSpace(formatValue(dateValue)))
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// updated возвращает дату самой новой записи ленты или now, если дат нет
+func (f *siteFeed) updated(now time.Time) time.Time {
+	var latest time.Time
+	for _, entry := range f.Entries {
+		if entry.Date.After(latest) {
+			latest = entry.Date
+		}
+	}
+	if latest.IsZero() {
+		return now
+	}
+	return latest
+}
+
+// feedFile возвращает путь файла ленты в формате format или пустую строку для неизвестного формата
+func feedFile(feed *siteFeed, format string) string {
+	switch format {
+	case feedRSS:
+		return path.Join(feed.Dir, feedRSSFile)
+	case feedAtom:
+		return path.Join(feed.Dir, feedAtomFile)
+	}
+	return ""
+}
+
+// feedOutputs возвращает пути файлов ленты во всех форматах feeds.formats
+func feedOutputs(opts *Options, feed *siteFeed) []string {
+	var outputs []string
+	for _, format := range opts.Config.Feeds.Formats {
+		if name := feedFile(feed, format); name != "" {
+			outputs = append(outputs, name)
+		}
+	}
+	return outputs
+}
+
+// writeFeeds записывает ленту во всех форматах feeds.formats. Возвращает пути записанных файлов.
+func writeFeeds(opts *Options, feed *siteFeed) ([]string, error) {
+	var outputs []string
+	for _, format := range opts.Config.Feeds.Formats {
+		name := feedFile(feed, format)
+		var content []byte
+		var err error
+		switch format {
+		case feedRSS:
+			content, err = renderRSS(feed, opts.Config)
+		case feedAtom:
+			content, err = renderAtom(feed, opts.Config, absoluteURL(siteBaseURL(opts.Config), "/"+name))
+		default:
+			continue
+		}
+		if err != nil {
+			return outputs, fmt.Errorf("ошибка при формировании ленты %s: %v", name, err)
+		}
+		if err := writeCollectionFile(opts, name, content); err != nil {
+			return outputs, err
+		}
+		outputs = append(outputs, name)
+	}
+	return outputs, nil
+}
+
+// Элементы RSS 2.0. Текст экранирует encoding/xml, недопустимые в XML символы заменяются на U+FFFD.
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// renderRSS формирует ленту RSS 2.0
+func renderRSS(feed *siteFeed, cfg *SiteConfig) ([]byte, error) {
+	doc := rssDocument{Version: "2.0", Channel: rssChannel{
+		Title:         feed.Title,
+		Link:          feed.Link,
+		Description:   siteString(cfg, "description", feed.Title),
+		Language:      siteString(cfg, "language", ""),
+		LastBuildDate: feed.updated(time.Now()).Format(time.RFC1123Z),
+		Generator:     "goferret",
+	}}
+	for _, entry := range feed.Entries {
+		item := rssItem{Title: entry.Title, Link: entry.URL, Description: entry.Summary, GUID: rssGUID{IsPermaLink: true, Value: entry.URL}}
+		if !entry.Date.IsZero() {
+			item.PubDate = entry.Date.Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return marshalFeed(doc)
+}
+
+// Элементы Atom 1.0
+type atomDocument struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang    string      `xml:"xml:lang,attr,omitempty"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	ID      string    `xml:"id"`
+	Updated string    `xml:"updated"`
+	Link    atomLink  `xml:"link"`
+	Summary *atomText `xml:"summary,omitempty"`
+}
+
+// renderAtom формирует ленту Atom 1.0 с адресом self. Записи без даты получают дату ленты,
+// потому что updated в Atom обязателен.
+func renderAtom(feed *siteFeed, cfg *SiteConfig, self string) ([]byte, error) {
+	updated := feed.updated(time.Now())
+	doc := atomDocument{
+		Lang:    siteString(cfg, "language", ""),
+		Title:   feed.Title,
+		ID:      feed.Link,
+		Updated: updated.Format(time.RFC3339),
+		Links:   []atomLink{{Href: feed.Link, Rel: "alternate"}, {Href: self, Rel: "self"}},
+	}
+	if author := siteString(cfg, "author", ""); author != "" {
+		doc.Author = &atomAuthor{Name: author}
+	}
+	for _, entry := range feed.Entries {
+		date := entry.Date
+		if date.IsZero() {
+			date = updated
+		}
+		e := atomEntry{Title: entry.Title, ID: entry.URL, Updated: date.Format(time.RFC3339), Link: atomLink{Href: entry.URL}}
+		if entry.Summary != "" {
+			e.Summary = &atomText{Type: "text", Body: entry.Summary}
+			if entry.HTML {
+				e.Summary.Type = "html"
+			}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return marshalFeed(doc)
+}
+
+// marshalFeed сериализует документ ленты с XML-заголовком
+func marshalFeed(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
+
+// siteString возвращает строковую переменную секции site или fallback
+func siteString(cfg *SiteConfig, key, fallback string) string {
+	if value := strings.TrimSpace(formatConfigScalar(cfg.Site[key])); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// siteFeedTask возвращает задачу ленты всего сайта или nil, если ленты отключены либо её файлы
+// уже заняты в owners (это попадает в отчёт). Если лента не изменилась с предыдущей сборки,
+// её запись переносится в next, а задача возвращается с Kept.
+func siteFeedTask(models []*Model, opts *Options, report *BuildReport, prev, next *BuildManifest, owners map[string]string) *TermTask {
+	if !feedsEnabled(opts.Config) {
+		return nil
+	}
+	base := siteBaseURL(opts.Config)
+	feed := newSiteFeed(siteString(opts.Config, "title", "goferret"), absoluteURL(base, "/"), "", models, opts.Config)
+	feedJSON, _ := json.Marshal(feed)
+	task := &TermTask{Feed: feed, Key: siteFeedKey, Fingerprint: contentHash(string(feedJSON))}
+	if err := task.claimOutputs(opts, owners); err != nil {
+		report.addError(stageCategory, task.Key, err)
+		return nil
+	}
+	if old := prev.Categories[task.Key]; old != nil && old.Fingerprint == task.Fingerprint && outputsExist(opts.OutputDir, old.Outputs) {
+		next.setCategory(task.Key, old)
+		task.Kept = true
+	}
+	return task
+}
@@ -0,0 +1,182 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты лент RSS и Atom.
+*/
+
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedsEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL interface{}
+		formats []string
+		want    bool
+	}{
+		{"есть baseURL", "https://example.com/", []string{feedRSS}, true},
+		{"нет baseURL", nil, []string{feedRSS, feedAtom}, false},
+		{"пустой baseURL", "  ", []string{feedRSS}, false},
+		{"нет форматов", "https://example.com", nil, false},
+	}
+	for _, tt := range tests {
+		cfg := defaultConfig()
+		cfg.Feeds.Formats = tt.formats
+		if tt.baseURL != nil {
+			cfg.Site["baseURL"] = tt.baseURL
+		}
+		if got := feedsEnabled(cfg); got != tt.want {
+			t.Errorf("%s: feedsEnabled = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAbsoluteURL(t *testing.T) {
+	tests := []struct {
+		base, path, want string
+	}{
+		{"https://example.com", "/", "https://example.com/"},
+		{"https://example.com/blog", "/a.html", "https://example.com/blog/a.html"},
+		{"https://example.com", "/заметки/", "https://example.com/%D0%B7%D0%B0%D0%BC%D0%B5%D1%82%D0%BA%D0%B8/"},
+		{"https://example.com", "/a b/c?d#e.html", "https://example.com/a%20b/c%3Fd%23e.html"},
+	}
+	for _, tt := range tests {
+		if got := absoluteURL(tt.base, tt.path); got != tt.want {
+			t.Errorf("absoluteURL(%q, %q) = %q, ожидалось %q", tt.base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNewSiteFeed(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 7, d, 0, 0, 0, 0, time.UTC) }
+	models := []*Model{
+		{ID: "a", URL: "/a.html", Data: map[string]string{"title": "A", "date": "2025-07-01"}, Dates: map[string]time.Time{"date": day(1)}},
+		{ID: "b", URL: "/b/", Data: map[string]string{"title": "B", "summary": "<p>b</p>", "date": "2025-07-03"},
+			Safe: map[string]bool{"summary": true}, Dates: map[string]time.Time{"date": day(3)}},
+		{ID: "nodate", URL: "/nodate.html", Data: map[string]string{"title": "Без даты"}},
+		{ID: "untitled", URL: "/untitled.html", Data: map[string]string{"summary": " кратко ", "date": "2025-07-02"}},
+		{ID: "old", URL: "/old.html", Data: map[string]string{"title": "Old", "updated": "2025-07-05"}, Dates: map[string]time.Time{"date": day(0)}},
+	}
+	cfg := defaultConfig()
+	cfg.Site["baseURL"] = "https://example.com/blog/"
+
+	tests := []struct {
+		name  string
+		date  string
+		limit int
+		want  []string // Адреса записей по порядку
+	}{
+		{"по дате, без даты последними", "date", 10, []string{"/b/", "/untitled.html", "/a.html", "/old.html", "/nodate.html"}},
+		{"ограничение feeds.limit", "date", 2, []string{"/b/", "/untitled.html"}},
+		{"другой атрибут даты", "updated", 2, []string{"/old.html", "/a.html"}},
+	}
+	for _, tt := range tests {
+		cfg.Feeds.Date, cfg.Feeds.Limit = tt.date, tt.limit
+		feed := newSiteFeed("Блог", "https://example.com/blog/", "", models, cfg)
+		var got []string
+		for _, entry := range feed.Entries {
+			got = append(got, strings.TrimPrefix(entry.URL, "https://example.com/blog"))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: записи %v, ожидались %v", tt.name, got, tt.want)
+		}
+	}
+
+	cfg.Feeds.Date, cfg.Feeds.Limit = "date", 10
+	entries := newSiteFeed("Блог", "https://example.com/blog/", "", models, cfg).Entries
+	want := []feedEntry{
+		{Title: "B", Summary: "<p>b</p>", HTML: true, URL: "https://example.com/blog/b/", Date: day(3)},
+		{Title: "untitled", Summary: "кратко", URL: "https://example.com/blog/untitled.html", Date: day(2)},
+	}
+	if !reflect.DeepEqual(entries[:2], want) {
+		t.Errorf("записи %+v, ожидались %+v", entries[:2], want)
+	}
+
+	// Адреса страниц с кириллицей и пробелами кодируются
+	cyrillic := []*Model{{ID: "заметки/a b", URL: "/заметки/a b.html", Data: map[string]string{}}}
+	if got := newSiteFeed("Блог", "", "", cyrillic, cfg).Entries[0].URL; got != "https://example.com/blog/%D0%B7%D0%B0%D0%BC%D0%B5%D1%82%D0%BA%D0%B8/a%20b.html" {
+		t.Errorf("адрес записи %q", got)
+	}
+}
+
+func TestRenderFeeds(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Site["baseURL"] = "https://example.com"
+	cfg.Site["author"] = "Автор"
+	feed := &siteFeed{
+		Title: `Заметки & <новости>`,
+		Link:  "https://example.com/",
+		Dir:   "main",
+		Entries: []feedEntry{
+			{Title: `"Go" & <XML>`, Summary: "<p>a & b</p>", HTML: true, URL: "https://example.com/a.html",
+				Date: time.Date(2025, 7, 2, 10, 30, 0, 0, time.UTC)},
+			{Title: "Без даты", Summary: "a < b", URL: "https://example.com/b.html"},
+		},
+	}
+	opts := &Options{OutputDir: t.TempDir(), Config: cfg}
+	outputs, err := writeFeeds(opts, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"main/rss.xml", "main/atom.xml"}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("записаны %v, ожидались %v", outputs, want)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(opts.OutputDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	rss := read("main/rss.xml")
+	for _, want := range []string{
+		`<rss version="2.0">`,
+		"<title>Заметки &amp; &lt;новости&gt;</title>",
+		"<link>https://example.com/</link>",
+		"<lastBuildDate>Wed, 02 Jul 2025 10:30:00 +0000</lastBuildDate>",
+		"<title>&#34;Go&#34; &amp; &lt;XML&gt;</title>",
+		"<link>https://example.com/a.html</link>",
+		"<description>&lt;p&gt;a &amp; b&lt;/p&gt;</description>",
+		"<pubDate>Wed, 02 Jul 2025 10:30:00 +0000</pubDate>",
+		`<guid isPermaLink="true">https://example.com/b.html</guid>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("в RSS нет %s:\n%s", want, rss)
+		}
+	}
+	var doc rssDocument
+	if err := xml.Unmarshal([]byte(rss), &doc); err != nil || len(doc.Channel.Items) != 2 || doc.Channel.Items[0].Title != `"Go" & <XML>` {
+		t.Errorf("RSS не разбирается обратно: %v %+v", err, doc.Channel.Items)
+	}
+
+	atom := read("main/atom.xml")
+	for _, want := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		"<title>Заметки &amp; &lt;новости&gt;</title>",
+		"<updated>2025-07-02T10:30:00Z</updated>",
+		`<link href="https://example.com/main/atom.xml" rel="self"></link>`,
+		"<name>Автор</name>",
+		"<id>https://example.com/a.html</id>",
+		`<summary type="html">&lt;p&gt;a &amp; b&lt;/p&gt;</summary>`,
+		`<summary type="text">a &lt; b</summary>`,
+	} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("в Atom нет %s:\n%s", want, atom)
+		}
+	}
+	// Запись без даты получает дату ленты
+	if strings.Count(atom, "<updated>2025-07-02T10:30:00Z</updated>") != 3 {
+		t.Errorf("даты записей Atom:\n%s", atom)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Фильтры переменных шаблона: {title|upper}, {content|truncate:200} и другие.
+*/
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FilterFunc преобразует значение переменной шаблона; args — аргументы фильтра после двоеточия
+type FilterFunc func(value string, args []string) (string, error)
+
+// filterDef описывает зарегистрированный фильтр
+type filterDef struct {
+	fn        FilterFunc
+	minArgs   int
+	maxArgs   int
+	unescaped bool // Результат является доверенным HTML и не экранируется
+}
+
+// templateFilters — реестр фильтров, доступных в шаблонах
+var templateFilters = make(map[string]filterDef)
+
+// registerFilter добавляет фильтр name, принимающий от minArgs до maxArgs аргументов.
+// При unescaped результат фильтра считается доверенным HTML и выводится без экранирования,
+// если фильтр стоит последним. Новые фильтры регистрируются в init() и сразу доступны всем шаблонам.
+func registerFilter(name string, minArgs, maxArgs int, unescaped bool, fn FilterFunc) {
+	templateFilters[name] = filterDef{fn: fn, minArgs: minArgs, maxArgs: maxArgs, unescaped: unescaped}
+}
+
+// dateLayouts — форматы, в которых распознаются даты для фильтра date
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006",
+}
+
+func init() {
+	registerFilter("upper", 0, 0, false, func(value string, args []string) (string, error) {
+		return strings.ToUpper(value), nil
+	})
+	registerFilter("lower", 0, 0, false, func(value string, args []string) (string, error) {
+		return strings.ToLower(value), nil
+	})
+	registerFilter("truncate", 1, 2, false, filterTruncate)
+	registerFilter("date", 1, 1, false, filterDate)
+	registerFilter("slug", 0, 0, false, func(value string, args []string) (string, error) {
+		return slugify(value), nil
+	})
+	registerFilter("striptags", 0, 0, false, func(value string, args []string) (string, error) {
+		return strings.TrimSpace(html.UnescapeString(mdHTMLTagStrip.ReplaceAllString(value, ""))), nil
+	})
+	registerFilter("default", 1, 1, false, func(value string, args []string) (string, error) {
+		if strings.TrimSpace(value) == "" {
+			return args[0], nil
+		}
+		return value, nil
+	})
+	// raw не меняет значение, а отключает экранирование
+	registerFilter("raw", 0, 0, true, func(value string, args []string) (string, error) {
+		return value, nil
+	})
+}
+
+// filterTruncate обрезает значение до args[0] символов по границе слова и добавляет args[1] (по умолчанию "…")
+func filterTruncate(value string, args []string) (string, error) {
+	limit, err := strconv.Atoi(args[0])
+	if err != nil || limit < 0 {
+		return "", fmt.Errorf("длина должна быть неотрицательным числом, получено %q", args[0])
+	}
+	suffix := "…"
+	if len(args) > 1 {
+		suffix = args[1]
+	}
+
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value, nil
+	}
+	cut := limit
+	for cut > 0 && !unicode.IsSpace(runes[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		cut = limit
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + suffix, nil
+}
+
+// filterDate переводит дату в формат Go args[0], например "2006-01-02" или "02.01.2006"
+func filterDate(value string, args []string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	if t, ok := parseDate(value); ok {
+		return t.Format(args[0]), nil
+	}
+	return "", fmt.Errorf("не удалось распознать дату %q", value)
+}
+
+// parseDate распознаёт дату в одном из форматов dateLayouts
+func parseDate(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// filterCall — вызов фильтра в подстановке с аргументами
+type filterCall struct {
+	name string
+	args []string
+}
+
+// parseFilters разбирает цепочку фильтров после первого "|": upper|truncate:200|default:"n/a".
+// Возвращает false, если текст не похож на цепочку фильтров, и ошибку для неизвестного фильтра.
+func parseFilters(s string) ([]filterCall, bool, error) {
+	var calls []filterCall
+	for {
+		s = strings.TrimLeft(s, " ")
+		n := 0
+		for n < len(s) && (s[n] >= 'a' && s[n] <= 'z' || s[n] >= 'A' && s[n] <= 'Z' || n > 0 && (s[n] >= '0' && s[n] <= '9' || s[n] == '_')) {
+			n++
+		}
+		if n == 0 {
+			return nil, false, nil
+		}
+		call := filterCall{name: s[:n]}
+		s = strings.TrimLeft(s[n:], " ")
+
+		if strings.HasPrefix(s, ":") {
+			s = s[1:]
+			for {
+				arg, rest, ok := parseFilterArg(strings.TrimLeft(s, " "))
+				if !ok {
+					return nil, false, nil
+				}
+				call.args = append(call.args, arg)
+				s = strings.TrimLeft(rest, " ")
+				if !strings.HasPrefix(s, ",") {
+					break
+				}
+				s = s[1:]
+			}
+		}
+
+		def, exists := templateFilters[call.name]
+		if !exists {
+			return nil, true, fmt.Errorf("неизвестный фильтр %s", call.name)
+		}
+		if len(call.args) < def.minArgs || len(call.args) > def.maxArgs {
+			return nil, true, fmt.Errorf("фильтр %s принимает от %d до %d аргументов, передано %d",
+				call.name, def.minArgs, def.maxArgs, len(call.args))
+		}
+		calls = append(calls, call)
+
+		switch {
+		case s == "":
+			return calls, true, nil
+		case s[0] == '|':
+			s = s[1:]
+		default:
+			return nil, false, nil
+		}
+	}
+}
+
+// parseFilterArg читает один аргумент фильтра: строку в двойных кавычках или слово до "," или "|"
+func parseFilterArg(s string) (string, string, bool) {
+	if strings.HasPrefix(s, `"`) {
+		var sb strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					sb.WriteByte(s[i])
+				}
+			case '"':
+				return sb.String(), s[i+1:], true
+			default:
+				sb.WriteByte(s[i])
+			}
+		}
+		return "", "", false
+	}
+	end := strings.IndexAny(s, ",|")
+	if end < 0 {
+		end = len(s)
+	}
+	arg := strings.TrimSpace(s[:end])
+	if arg == "" || strings.ContainsAny(arg, `" `) {
+		return "", "", false
+	}
+	return arg, s[end:], true
+}
+
+// applyFilters применяет цепочку фильтров к значению
+func applyFilters(value string, filters []filterCall) (string, error) {
+	for _, call := range filters {
+		var err error
+		if value, err = templateFilters[call.name].fn(value, call.args); err != nil {
+			return "", fmt.Errorf("фильтр %s: %v", call.name, err)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,124 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты фильтров шаблона.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		filters string
+		want    string
+		err     string // Фрагмент текста ошибки; пусто — ошибки нет
+	}{
+		{"upper и lower", "Привет", "upper|lower", "привет", ""},
+		{"truncate по границе слова", "Быстрая рыжая лиса", "truncate:10", "Быстрая…", ""},
+		{"truncate без обрезки", "коротко", "truncate:10", "коротко", ""},
+		{"truncate ровно по длине", "ровно", "truncate:5", "ровно", ""},
+		{"truncate длинного слова", "Превысокомногорассмотрительствующий", "truncate:5", "Превы…", ""},
+		{"truncate с суффиксом", "one two three", `truncate:8," ..."`, "one two ...", ""},
+		{"truncate с пустым суффиксом", "one two three", `truncate:9,""`, "one two", ""},
+		{"truncate с нечисловой длиной", "x", "truncate:abc", "", `длина должна быть неотрицательным числом, получено "abc"`},
+		{"truncate с отрицательной длиной", "x", "truncate:-1", "", "неотрицательным числом"},
+		{"date с форматом", "2025-07-02", `date:"02.01.2006"`, "02.07.2025", ""},
+		{"date из RFC 3339", "2025-07-02T10:30:00Z", `date:"2006-01-02 15:04"`, "2025-07-02 10:30", ""},
+		{"date из формата ДД.ММ.ГГГГ", "02.07.2025", "date:2006-01-02", "2025-07-02", ""},
+		{"date пустого значения", " ", "date:2006", "", ""},
+		{"date нераспознанной даты", "вчера", "date:2006", "", `не удалось распознать дату "вчера"`},
+		{"slug кириллицы", "  Привет, Мир! Go_1.22 ", "slug", "привет-мир-go-122", ""},
+		{"striptags", "<p>Hello <b>мир</b> &amp; всё</p>", "striptags", "Hello мир & всё", ""},
+		{"default для пустого значения", " ", `default:"n/a"`, "n/a", ""},
+		{"default для непустого значения", "x", `default:"n/a"`, "x", ""},
+		{"цепочка", "<p>Hello World</p>", "striptags|truncate:7|upper", "HELLO…", ""},
+	}
+	for _, tt := range tests {
+		calls, ok, err := parseFilters(tt.filters)
+		if !ok || err != nil {
+			t.Errorf("%s: разбор %q: ok %v, ошибка %v", tt.name, tt.filters, ok, err)
+			continue
+		}
+		got, err := applyFilters(tt.value, calls)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%s: ошибка %v", tt.name, err)
+		case got != tt.want:
+			t.Errorf("%s: получено %q, ожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseFiltersErrors(t *testing.T) {
+	tests := []struct {
+		filters string
+		ok      bool   // Текст похож на цепочку фильтров
+		err     string // Ожидаемая ошибка; пусто — ошибки нет
+	}{
+		{"shout", true, "неизвестный фильтр shout"},
+		{"upper|shout:1", true, "неизвестный фильтр shout"},
+		{"truncate", true, "фильтр truncate принимает от 1 до 2 аргументов, передано 0"},
+		{"truncate:1,2,3", true, "фильтр truncate принимает от 1 до 2 аргументов, передано 3"},
+		{"upper:x", true, "фильтр upper принимает от 0 до 0 аргументов, передано 1"},
+		{"date", true, "фильтр date принимает от 1 до 1 аргументов, передано 0"},
+		{"default:a,b", true, "фильтр default принимает от 1 до 1 аргументов, передано 2"},
+		// Не цепочка фильтров: подстановка остаётся текстом
+		{"", false, ""},
+		{"upper x", false, ""},
+		{`default:"n/a`, false, ""},
+		{"truncate:a b", false, ""},
+		{"upper|", false, ""},
+	}
+	for _, tt := range tests {
+		_, ok, err := parseFilters(tt.filters)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if ok != tt.ok || got != tt.err {
+			t.Errorf("parseFilters(%q): ok %v, ошибка %q; ожидалось %v и %q", tt.filters, ok, got, tt.ok, tt.err)
+		}
+	}
+}
+
+func TestRegisterFilterUnescaped(t *testing.T) {
+	registerFilter("testbold", 0, 0, true, func(value string, args []string) (string, error) {
+		return "<b>" + value + "</b>", nil
+	})
+	registerFilter("testwrap", 0, 0, false, func(value string, args []string) (string, error) {
+		return "<" + value + ">", nil
+	})
+	defer delete(templateFilters, "testbold")
+	defer delete(templateFilters, "testwrap")
+
+	tests := []struct{ tpl, want string }{
+		{"{v|testbold}", "<b>x</b>"},
+		{"{v|testwrap}", "&lt;x&gt;"},
+		{"{v|testbold|upper}", "&lt;B&gt;X&lt;/B&gt;"},
+		{"{v|upper|raw}", "X"},
+	}
+	for _, tt := range tests {
+		tpl, err := compileTemplate("test", tt.tpl)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.tpl, err)
+		}
+		got, err := tpl.Execute(map[string]string{"v": "x"}, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.tpl, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, ожидалось %q", tt.tpl, got, tt.want)
+		}
+	}
+}
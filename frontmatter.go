@@ -0,0 +1,446 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Front matter однофайловых страниц (YAML, TOML, JSON) и разбор подмножества YAML.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Разделители front matter
+const (
+	frontMatterYAML = "---"
+	frontMatterTOML = "+++"
+)
+
+// parseFrontMatter отделяет заголовок с метаданными от тела страницы.
+// YAML ограничивается строками ---, TOML — строками +++, JSON — объектом в начале файла.
+// Если заголовка нет, весь текст считается телом.
+func parseFrontMatter(src string) (map[string]interface{}, string, error) {
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+
+	if strings.HasPrefix(src, "{") {
+		dec := json.NewDecoder(strings.NewReader(src))
+		meta := make(map[string]interface{})
+		if err := dec.Decode(&meta); err != nil {
+			return nil, "", fmt.Errorf("ошибка в JSON front matter: %v", err)
+		}
+		return meta, src[dec.InputOffset():], nil
+	}
+
+	for _, delim := range []string{frontMatterYAML, frontMatterTOML} {
+		if !strings.HasPrefix(src, delim+"\n") {
+			continue
+		}
+		rest := src[len(delim)+1:]
+		header, body := rest, ""
+		if strings.HasPrefix(rest, delim+"\n") || rest == delim {
+			header, body = "", strings.TrimPrefix(rest[len(delim):], "\n")
+		} else if end := strings.Index(rest, "\n"+delim+"\n"); end >= 0 {
+			header, body = rest[:end], rest[end+len(delim)+2:]
+		} else if strings.HasSuffix(rest, "\n"+delim) {
+			header = strings.TrimSuffix(rest, "\n"+delim)
+		} else {
+			return nil, "", fmt.Errorf("front matter не закрыт строкой %s", delim)
+		}
+
+		var meta map[string]interface{}
+		var err error
+		if delim == frontMatterTOML {
+			meta, err = parseTOML(header)
+		} else {
+			meta, err = parseYAML(header)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("ошибка в front matter: %v", err)
+		}
+		return meta, body, nil
+	}
+
+	return map[string]interface{}{}, src, nil
+}
+
+// yamlLine — значимая строка YAML-документа
+type yamlLine struct {
+	indent int
+	text   string
+	num    int
+}
+
+// yamlParser разбирает подмножество YAML, достаточное для front matter:
+// отображения, последовательности (блочные и в квадратных скобках), строки в кавычках,
+// числа, логические значения и блочные скаляры | и >.
+type yamlParser struct {
+	lines []yamlLine
+	raw   []string
+	pos   int
+}
+
+// parseYAML разбирает YAML-документ, корнем которого является отображение
+func parseYAML(src string) (map[string]interface{}, error) {
+	p := &yamlParser{raw: strings.Split(src, "\n")}
+	for i, line := range p.raw {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return nil, fmt.Errorf("yaml, строка %d: табуляция в отступе не допускается", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(line) - len(strings.TrimLeft(line, " ")), text: strings.TrimRight(strings.TrimLeft(line, " "), " \t"), num: i})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "неожиданный отступ")
+	}
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("yaml: ожидалось отображение ключ: значение")
+	}
+	return root, nil
+}
+
+func (p *yamlParser) errorf(line yamlLine, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml, строка %d: %s", line.num+1, fmt.Sprintf(format, args...))
+}
+
+// parseBlock разбирает отображение или последовательность с отступом indent
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isYAMLSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseMapping разбирает строки вида key: value с одинаковым отступом
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, p.errorf(line, "неожиданный отступ")
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, p.errorf(line, "ожидалась строка вида ключ: значение")
+		}
+		if _, exists := result[key]; exists {
+			return nil, p.errorf(line, "ключ %q определён повторно", key)
+		}
+		p.pos++
+
+		value, err := p.parseValue(line, indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// parseSequence разбирает элементы "- value" с одинаковым отступом
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || !isYAMLSequenceItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, p.errorf(line, "неожиданный отступ")
+		}
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+
+		if _, _, isMap := splitYAMLKey(rest); isMap && !strings.HasPrefix(rest, "\"") && !strings.HasPrefix(rest, "'") {
+			// Элемент-отображение: "- key: value" продолжается строками с отступом после "- "
+			itemIndent := line.indent + len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{indent: itemIndent, text: rest, num: line.num}
+			item, err := p.parseMapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, item)
+			continue
+		}
+
+		p.pos++
+		value, err := p.parseValue(line, indent, rest)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// parseValue разбирает значение после ключа или маркера элемента последовательности
+func (p *yamlParser) parseValue(line yamlLine, indent int, rest string) (interface{}, error) {
+	switch {
+	case rest == "":
+		// Вложенный блок на следующих строках
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isYAMLSequenceItem(next.text) && !isYAMLSequenceItem(line.text)) {
+				return p.parseBlock(next.indent)
+			}
+		}
+		return "", nil
+	case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+		return p.parseBlockScalar(line, indent, rest)
+	case strings.HasPrefix(rest, "["):
+		return parseYAMLFlowSequence(rest)
+	case strings.HasPrefix(rest, "{"):
+		return parseYAMLFlowMapping(rest)
+	}
+	return parseYAMLScalar(rest)
+}
+
+// parseBlockScalar разбирает многострочное значение: | сохраняет переводы строк, > склеивает строки
+func (p *yamlParser) parseBlockScalar(line yamlLine, indent int, header string) (string, error) {
+	folded := header[0] == '>'
+	chomp := strings.TrimSpace(header[1:])
+
+	// Строки блочного скаляра берутся из исходного текста, включая пустые
+	var body []string
+	end := len(p.raw)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		p.pos++
+	}
+	if p.pos < len(p.lines) {
+		end = p.lines[p.pos].num
+	}
+	body = append(body, p.raw[line.num+1:end]...)
+	for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+		body = body[:len(body)-1]
+	}
+
+	common := -1
+	for _, l := range body {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " "))
+		if common < 0 || n < common {
+			common = n
+		}
+	}
+	for i, l := range body {
+		if len(l) >= common && common > 0 {
+			body[i] = l[common:]
+		} else {
+			body[i] = strings.TrimLeft(l, " ")
+		}
+	}
+
+	var text string
+	if folded {
+		var sb strings.Builder
+		for i, l := range body {
+			// Соседние строки склеиваются пробелом, каждая пустая строка даёт перевод строки
+			switch {
+			case i == 0:
+			case l == "":
+				sb.WriteByte('\n')
+			case body[i-1] != "":
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(l)
+		}
+		text = sb.String()
+	} else {
+		text = strings.Join(body, "\n")
+	}
+	if chomp != "-" && text != "" {
+		text += "\n"
+	}
+	return text, nil
+}
+
+// splitYAMLKey делит строку "key: value" на ключ и значение
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		quote := text[0]
+		end := strings.IndexByte(text[1:], quote)
+		if end < 0 {
+			return "", "", false
+		}
+		key := text[1 : end+1]
+		rest := text[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(stripYAMLComment(rest[1:])), true
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			key := strings.TrimSpace(text[:i])
+			if key == "" {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(stripYAMLComment(text[i+1:])), true
+		}
+		if text[i] == ' ' && i+1 < len(text) && text[i+1] == '#' {
+			break
+		}
+	}
+	return "", "", false
+}
+
+// stripYAMLComment удаляет комментарий " # ..." вне кавычек
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if strings.TrimSpace(s[:i]) == "" || strings.ContainsAny(s[i-1:i], "[{, ") {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// parseYAMLScalar разбирает скаляр: строку в кавычках, число, логическое значение или простой текст
+func parseYAMLScalar(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "\""):
+		value, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: неверная строка %s", s)
+		}
+		return value, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("yaml: неверная строка %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	case "null", "~":
+		return "", nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xX") {
+		return f, nil
+	}
+	return s, nil
+}
+
+// splitYAMLFlow делит содержимое [..] или {..} по запятым верхнего уровня
+func splitYAMLFlow(s string) []string {
+	var parts []string
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// parseYAMLFlowSequence разбирает последовательность вида [a, "b", 3]
+func parseYAMLFlowSequence(s string) ([]interface{}, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("yaml: незакрытая последовательность %s", s)
+	}
+	result := make([]interface{}, 0)
+	for _, part := range splitYAMLFlow(s[1 : len(s)-1]) {
+		value, err := parseYAMLFlowItem(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// parseYAMLFlowMapping разбирает отображение вида {a: 1, b: "x"}
+func parseYAMLFlowMapping(s string) (map[string]interface{}, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("yaml: незакрытое отображение %s", s)
+	}
+	result := make(map[string]interface{})
+	for _, part := range splitYAMLFlow(s[1 : len(s)-1]) {
+		key, rest, ok := splitYAMLKey(strings.TrimSpace(part))
+		if !ok {
+			return nil, fmt.Errorf("yaml: ожидалась пара ключ: значение в %s", s)
+		}
+		value, err := parseYAMLFlowItem(rest)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func parseYAMLFlowItem(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "["):
+		return parseYAMLFlowSequence(s)
+	case strings.HasPrefix(s, "{"):
+		return parseYAMLFlowMapping(s)
+	}
+	return parseYAMLScalar(s)
+}
@@ -0,0 +1,122 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты разбора front matter и YAML.
+*/
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantMeta map[string]interface{}
+		wantBody string
+	}{
+		{"без заголовка", "# Заголовок\n", map[string]interface{}{}, "# Заголовок\n"},
+		{"YAML", "---\ntitle: Привет\ntags: [go, site]\n---\nТекст\n",
+			map[string]interface{}{"title": "Привет", "tags": []interface{}{"go", "site"}}, "Текст\n"},
+		{"TOML", "+++\ntitle = \"Привет\"\nweight = 2\n+++\nТекст",
+			map[string]interface{}{"title": "Привет", "weight": int64(2)}, "Текст"},
+		{"JSON", "{\"title\": \"Привет\"}\nТекст", map[string]interface{}{"title": "Привет"}, "\nТекст"},
+		{"пустой заголовок", "---\n---\nТекст", map[string]interface{}{}, "Текст"},
+		{"заголовок без тела", "---\ntitle: T\n---", map[string]interface{}{"title": "T"}, ""},
+		{"CRLF и BOM", "\ufeff---\r\ntitle: T\r\n---\r\nТекст\r\n", map[string]interface{}{"title": "T"}, "Текст\n"},
+		{"--- внутри тела", "---\na: 1\n---\nx\n---\ny\n", map[string]interface{}{"a": int64(1)}, "x\n---\ny\n"},
+	}
+	for _, tt := range tests {
+		meta, body, err := parseFrontMatter(tt.src)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(meta, tt.wantMeta) {
+			t.Errorf("%s: метаданные %#v, ожидалось %#v", tt.name, meta, tt.wantMeta)
+		}
+		if body != tt.wantBody {
+			t.Errorf("%s: тело %q, ожидалось %q", tt.name, body, tt.wantBody)
+		}
+	}
+}
+
+func TestParseFrontMatterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"незакрытый YAML", "---\ntitle: T\nТекст\n", "не закрыт строкой ---"},
+		{"незакрытый TOML", "+++\ntitle = \"T\"\n", "не закрыт строкой +++"},
+		{"ошибка TOML", "+++\ntitle = \n+++\n", "toml, строка 1"},
+		{"ошибка JSON", "{\"title\": }\n", "JSON front matter"},
+		{"табуляция в YAML", "---\na:\n\tb: 1\n---\n", "yaml, строка 2: табуляция"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseFrontMatter(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]interface{}
+	}{
+		{"скаляры", "s: текст\nq: \"a\\tb\"\nl: 'it''s'\ni: 42\nf: 1.5\nb: yes\nn: ~\nhex: 0x1F",
+			map[string]interface{}{"s": "текст", "q": "a\tb", "l": "it's", "i": int64(42), "f": 1.5, "b": true, "n": "", "hex": "0x1F"}},
+		{"комментарии", "# начало\na: 1 # число\nurl: http://x/#anchor\n",
+			map[string]interface{}{"a": int64(1), "url": "http://x/#anchor"}},
+		{"вложенные отображения", "author:\n  name: A\n  social:\n    gh: a\nx: 1",
+			map[string]interface{}{"author": map[string]interface{}{"name": "A", "social": map[string]interface{}{"gh": "a"}}, "x": int64(1)}},
+		{"блочная последовательность", "tags:\n  - go\n  - \"site\"\nsame:\n- a\n- b",
+			map[string]interface{}{"tags": []interface{}{"go", "site"}, "same": []interface{}{"a", "b"}}},
+		{"последовательность отображений", "links:\n  - name: a\n    url: /a\n  - name: b",
+			map[string]interface{}{"links": []interface{}{map[string]interface{}{"name": "a", "url": "/a"}, map[string]interface{}{"name": "b"}}}},
+		{"потоковые значения", "a: [1, \"x, y\", [2]]\nm: {k: v, n: 1}",
+			map[string]interface{}{"a": []interface{}{int64(1), "x, y", []interface{}{int64(2)}}, "m": map[string]interface{}{"k": "v", "n": int64(1)}}},
+		{"ключ в кавычках", "\"a: b\": 1", map[string]interface{}{"a: b": int64(1)}},
+		{"блочный скаляр |", "text: |\n  строка 1\n\n  строка 2\nnext: 1",
+			map[string]interface{}{"text": "строка 1\n\nстрока 2\n", "next": int64(1)}},
+		{"блочный скаляр >-", "text: >-\n  a\n  b\n\n  c\n",
+			map[string]interface{}{"text": "a b\nc"}},
+		{"пустое значение", "a:\nb: 1", map[string]interface{}{"a": "", "b": int64(1)}},
+	}
+	for _, tt := range tests {
+		got, err := parseYAML(tt.src)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\nполучено  %#v\nожидалось %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"не отображение", "- a\n- b", "ожидалось отображение"},
+		{"неожиданный отступ", "a: 1\n    b: 2", "yaml, строка 2"},
+		{"неверная строка", "a: \"x", "неверная строка"},
+	}
+	for _, tt := range tests {
+		_, err := parseYAML(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.want)
+		}
+	}
+}
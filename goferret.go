@@ -11,24 +11,21 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
-	"encoding/json"
 	"sync"
 	"time"
 )
 
 // Сообщения для пользователя
 const (
-	msgTemplatesDirNotFound = "Ошибка: директория 'templates' не найдена"
-	msgContentDirNotFound   = "Ошибка: директория 'content' не найдена"
+	msgTemplatesDirNotFound = "Ошибка: директория шаблонов '%s' не найдена (флаг %s)\n"
+	msgContentDirNotFound   = "Ошибка: директория содержимого '%s' не найдена (флаг %s)\n"
+	msgBlocksDirNotFound    = "Ошибка: директория блоков '%s' не найдена (флаг %s)\n"
 	msgErrorReadingContent  = "Ошибка при чтении директории content: %v\n"
-	msgErrorProcessingPage  = "Ошибка при обработке страницы %s: %v\n"
-	msgWarningNoTemplate    = "Предупреждение: для страницы %s не указан шаблон\n"
-	msgErrorLoadingTemplate = "Ошибка при загрузке шаблона для страницы %s: %v\n"
-	msgErrorRendering       = "Ошибка при рендеринге шаблона для страницы %s: %v\n"
-	msgErrorWritingOutput   = "Ошибка при записи вывода для страницы %s: %v\n"
+	msgNoTemplate           = "не указан шаблон страницы"
 	msgGenerated            = "Сгенерировано: %s\n"
 	msgSiteGenerationDone   = "Генерация сайта завершена!"
 	msgErrorReadingTemplate = "Ошибка при чтении шаблона %s: %v"
@@ -36,45 +33,46 @@ const (
 	msgErrorReadingPageDir  = "Ошибка при чтении директории страницы %s: %v"
 	msgErrorReadingAttr     = "Ошибка при чтении атрибута %s для страницы %s: %v"
 	msgErrorReadingCategory = "Ошибка при чтении категории для страницы %s: %v"
+	msgErrorDuplicateAttr   = "Атрибут %s страницы %s задан дважды: %s и %s"
+	msgErrorReadingPageFile = "Ошибка при чтении файла страницы %s: %v"
+	msgErrorFrontMatter     = "Ошибка в заголовке страницы %s: %v"
+	msgErrorDuplicatePage   = "страница %s задана дважды: директорией %s и файлом %s"
+	msgErrorInvalidSlug     = "Недопустимый slug %q для страницы %s"
+	msgErrorOutputConflict  = "Страницы %s и %s записываются в один файл %s"
+	msgErrorBlocks          = "Ошибка при обработке блоков: %v\n"
+	msgElapsed              = "Время выполнения: %d мс\n"
 )
 
 // Model представляет страницу с её атрибутами и шаблоном
 type Model struct {
-	ID       string
+	ID       string // Путь страницы относительно content без расширения, например docs/setup
 	Data     map[string]string
+	Safe     map[string]bool      // Атрибуты с доверенным HTML (Markdown, блоки), которые не экранируются
+	Dates    map[string]time.Time // Распознанные даты date, publishDate и expiryDate
+	Draft    bool                 // Черновик из draft.val; собирается только с -drafts
+	ModTime  time.Time            // Время последнего изменения файлов страницы
+	Assets   []string             // Файлы директории страницы, которые копируются рядом с её результатом
 	Template string
 	Category string
+	Path     string // Путь к файлу результата относительно директории сборки
+	URL      string // Адрес страницы на сайте
 }
 
-// parseTemplateVars извлекает все переменные шаблона из файла шаблона
-func parseTemplateVars(templateContent string) map[string]string {
-	re := regexp.MustCompile(`\{([^}]+)\}`)
-	matches := re.FindAllStringSubmatch(templateContent, -1)
-
-	vars := make(map[string]string)
-	for _, match := range matches {
-		if len(match) > 1 {
-			vars[match[1]] = ""
-		}
-	}
-	return vars
-}
-
-// loadTemplate загружает файл шаблона и возвращает его содержимое и переменные
-func loadTemplate(templateName string) (string, map[string]string, error) {
-	path := filepath.Join("templates", templateName+".tpl")
-	content, err := ioutil.ReadFile(path)
+// pageIDFromPath возвращает идентификатор страницы: путь относительно contentDir без расширения .md
+func pageIDFromPath(contentDir, pagePath string) string {
+	rel, err := filepath.Rel(contentDir, pagePath)
 	if err != nil {
-		return "", nil, fmt.Errorf(msgErrorReadingTemplate, templateName, err)
+		rel = filepath.Base(pagePath)
 	}
-
-	templateStr := string(content)
-	vars := parseTemplateVars(templateStr)
-	return templateStr, vars, nil
+	return strings.TrimSuffix(filepath.ToSlash(rel), ".md")
 }
 
-// processPage обрабатывает директорию одной страницы и возвращает Model
-func processPage(pagePath string, blocks map[string]string) (*Model, error) {
+// processPage обрабатывает директорию одной страницы и возвращает Model.
+// Файлы content/<id>.md обрабатываются как однофайловые страницы с front matter.
+func processPage(contentDir, pagePath string, blocks map[string]string, rawHTML bool) (*Model, error) {
+	if filepath.Ext(pagePath) == ".md" {
+		return processPageFile(contentDir, pagePath, blocks, rawHTML)
+	}
 	// Print blocks hashmap to the terminal
 	/*
 	fmt.Println("Blocks hashmap in processPage:")
@@ -82,10 +80,12 @@ func processPage(pagePath string, blocks map[string]string) (*Model, error) {
 		fmt.Printf("  %s: %s\n", k, v)
 	}
 	*/
-	pageID := filepath.Base(pagePath)
+	pageID := pageIDFromPath(contentDir, pagePath)
 	model := &Model{
-		ID:   pageID,
-		Data: make(map[string]string),
+		ID:    pageID,
+		Data:  make(map[string]string),
+		Safe:  make(map[string]bool),
+		Dates: make(map[string]time.Time),
 	}
 
 	// Read category.val if exists
@@ -108,19 +108,40 @@ func processPage(pagePath string, blocks map[string]string) (*Model, error) {
 		model.Template = strings.TrimSpace(string(templateName))
 	}
 
-	// Чтение всех файлов attribute.val
+	// Чтение всех файлов attribute.val и attribute.md
 	files, err := ioutil.ReadDir(pagePath)
 	if err != nil {
 		return nil, fmt.Errorf(msgErrorReadingPageDir, pageID, err)
 	}
 
+	attrFiles := make(map[string]string)
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".val") {
-			attrName := strings.TrimSuffix(file.Name(), ".val")
-			content, err := ioutil.ReadFile(filepath.Join(pagePath, file.Name()))
-			if err != nil {
-				return nil, fmt.Errorf(msgErrorReadingAttr, attrName, pageID, err)
-			}
+		if !file.IsDir() && file.ModTime().After(model.ModTime) {
+			model.ModTime = file.ModTime()
+		}
+		if !file.IsDir() && !isPageFile(file.Name()) {
+			model.Assets = append(model.Assets, filepath.Join(pagePath, file.Name()))
+			continue
+		}
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != ".val" && ext != ".md") {
+			continue
+		}
+		attrName := strings.TrimSuffix(file.Name(), ext)
+		if other, exists := attrFiles[attrName]; exists {
+			return nil, fmt.Errorf(msgErrorDuplicateAttr, attrName, pageID, other, file.Name())
+		}
+		attrFiles[attrName] = file.Name()
+
+		content, err := ioutil.ReadFile(filepath.Join(pagePath, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf(msgErrorReadingAttr, attrName, pageID, err)
+		}
+		if ext == ".md" {
+			// Атрибуты в Markdown преобразуются в HTML до подстановки в шаблон
+			model.Data[attrName] = renderMarkdown(string(content), rawHTML)
+			model.Safe[attrName] = true
+		} else {
 			model.Data[attrName] = strings.TrimSpace(string(content))
 		}
 	}
@@ -138,128 +159,119 @@ func processPage(pagePath string, blocks map[string]string) (*Model, error) {
 	}
 	fmt.Println("---")
 	*/
+	if err := parsePublishing(model); err != nil {
+		return nil, err
+	}
 	return model, nil
 }
 
-// renderTemplate применяет данные модели к шаблону
-func renderTemplate(templateStr string, model *Model) (string, error) {
-	re := regexp.MustCompile(`\{([^}]+)\}`)
-	result := re.ReplaceAllStringFunc(templateStr, func(match string) string {
-		key := match[1 : len(match)-1] // Удаляем фигурные скобки
-		if value, exists := model.Data[key]; exists {
-			return value
-		}
-		return match // Возвращаем оригинал, если не найдено
-	})
-	return result, nil
-}
-
-// Add new function to generate category files
-type CategoryTask struct {
-	Category string
-	Items    []map[string]string
-}
+// processPageFile обрабатывает однофайловую страницу content/<id>.md.
+// Front matter заполняет атрибуты, шаблон и категорию, а тело в Markdown становится атрибутом content.
+func processPageFile(contentDir, pagePath string, blocks map[string]string, rawHTML bool) (*Model, error) {
+	pageID := pageIDFromPath(contentDir, pagePath)
+	model := &Model{
+		ID:    pageID,
+		Data:  make(map[string]string),
+		Safe:  make(map[string]bool),
+		Dates: make(map[string]time.Time),
+	}
 
-// processCategory handles the generation of JSON and HTML files for a single category
-func processCategory(task CategoryTask, blocks map[string]string) error {
-	// Generate JSON file
-	jsonData, err := json.MarshalIndent(task.Items, "", "  ")
+	source, err := ioutil.ReadFile(pagePath)
 	if err != nil {
-		return fmt.Errorf("ошибка при маршалинге JSON для категории %s: %v", task.Category, err)
+		return nil, fmt.Errorf(msgErrorReadingPageFile, pageID, err)
 	}
-	jsonPath := filepath.Join("build", fmt.Sprintf("%s.json", task.Category))
-	if err := ioutil.WriteFile(jsonPath, jsonData, 0644); err != nil {
-		return fmt.Errorf("ошибка при записи JSON файла для категории %s: %v", task.Category, err)
+	if info, err := os.Stat(pagePath); err == nil {
+		model.ModTime = info.ModTime()
 	}
-
-	// Generate HTML file
-	htmlTplPath := filepath.Join("collections", "category.tpl")
-	htmlBytes, err := ioutil.ReadFile(htmlTplPath)
+	meta, body, err := parseFrontMatter(string(source))
 	if err != nil {
-		return fmt.Errorf("ошибка при чтении шаблона категории: %v", err)
+		return nil, fmt.Errorf(msgErrorFrontMatter, pageID, err)
 	}
-	htmlContent := string(htmlBytes)
-
-	htmlContent = strings.ReplaceAll(htmlContent, "{{CATEGORY}}", task.Category)
 
-	// Replace {key} with blocks[key] for all keys in blocks
-	for k, v := range blocks {
-		htmlContent = strings.ReplaceAll(htmlContent, "{"+k+"}", v)
+	for key, value := range meta {
+		flattenConfigValue(key, value, model.Data)
 	}
+	// Как и template.setting, шаблон не является атрибутом страницы
+	model.Template = strings.TrimSpace(model.Data["template"])
+	delete(model.Data, "template")
+	model.Category = strings.TrimSpace(model.Data["category"])
 
-	htmlPath := filepath.Join("build", task.Category+".html")
-	if err := ioutil.WriteFile(htmlPath, []byte(htmlContent), 0644); err != nil {
-		return fmt.Errorf("ошибка при записи HTML файла: %v", err)
+	if _, exists := model.Data["content"]; exists {
+		return nil, fmt.Errorf(msgErrorDuplicateAttr, "content", pageID, "front matter", "тело страницы")
 	}
+	model.Data["content"] = renderMarkdown(body, rawHTML)
+	model.Safe["content"] = true
 
-	return nil
+	for k, v := range blocks {
+		model.Data[k] = v
+	}
+	if err := parsePublishing(model); err != nil {
+		return nil, err
+	}
+	return model, nil
 }
 
-// generateCategoryFiles now processes categories in parallel using a worker pool
-func generateCategoryFiles(models []*Model, blocks map[string]string) error {
-	// Group models by category
-	categories := make(map[string][]map[string]string)
-	for _, model := range models {
-		if model.Category == "" {
-			continue
-		}
-
-		if _, exists := categories[model.Category]; !exists {
-			categories[model.Category] = make([]map[string]string, 0)
-		}
-
-		item := map[string]string{
-			"title": model.Data["title"],
-			"url":   fmt.Sprintf("/%s.html", model.ID),
+// assignOutputPath вычисляет путь к файлу результата и адрес страницы по её идентификатору.
+// Атрибут slug заменяет последнюю часть пути. Страницы index становятся index.html своей директории,
+// остальные — <id>.html, а при prettyURLs — <id>/index.html.
+func assignOutputPath(model *Model, prettyURLs bool) error {
+	segments := strings.Split(model.ID, "/")
+	if slug := strings.TrimSpace(model.Data["slug"]); slug != "" {
+		if strings.ContainsAny(slug, `/\`) || slug == "." || slug == ".." {
+			return fmt.Errorf(msgErrorInvalidSlug, slug, model.ID)
 		}
-		categories[model.Category] = append(categories[model.Category], item)
-	}
-
-	// Create build directory if not exists
-	if _, err := os.Stat("build"); os.IsNotExist(err) {
-		os.Mkdir("build", 0755)
+		segments[len(segments)-1] = slug
 	}
+	name := segments[len(segments)-1]
+	dir := strings.Join(segments[:len(segments)-1], "/")
 
-	chTasks := make(chan CategoryTask, len(categories))
-	chErrors := make(chan error, len(categories))
-	var wg sync.WaitGroup
-
-	numWorkers := 4 // Adjust as needed
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for task := range chTasks {
-				if err := processCategory(task, blocks); err != nil {
-					chErrors <- err
-				}
-			}
-		}()
+	switch {
+	case name == "index":
+		model.Path = path.Join(dir, "index.html")
+		model.URL = path.Join("/", dir) + "/"
+	case prettyURLs:
+		model.Path = path.Join(dir, name, "index.html")
+		model.URL = path.Join("/", dir, name) + "/"
+	default:
+		model.Path = path.Join(dir, name+".html")
+		model.URL = path.Join("/", dir, name+".html")
 	}
-
-	for category, items := range categories {
-		chTasks <- CategoryTask{Category: category, Items: items}
+	if model.URL == "//" {
+		model.URL = "/"
 	}
-	close(chTasks)
-
-	wg.Wait()
-	close(chErrors)
+	return nil
+}
 
-	for err := range chErrors {
-		if err != nil {
-			return err
-		}
-	}
+// renderTemplate применяет данные модели к шаблону
+func renderTemplate(tpl *Template, model *Model, assets *assetIndex) ([]byte, error) {
+	return tpl.ExecuteBytes(model.Data, model.Safe, assets)
+}
 
-	return nil
+// BlockSet holds the global blocks compiled as templates and ordered so that every block
+// comes after the blocks it references.
+type BlockSet struct {
+	Data      map[string]string // Block sources and site variables used to initialise page models
+	order     []string
+	templates map[string]*Template
+	static    map[string]bool     // Blocks that use only other static blocks and site variables
+	deps      map[string][]string // Blocks referenced by each block
+	assets    *assetIndex         // Files from static/ of this build for {asset} and {integrity}
+
+	staticOnce     sync.Once
+	staticRendered map[string]string
+	staticErr      error
 }
 
-// getBlocksSubModel reads all .tpl files from ./blocks, stores their contents in a Blocks map,
-// and substitutes {blockname} for other blocks using strings.Replace (no recursion, no self-reference).
-func getBlocksSubModel() (map[string]string, error) {
-	blocksDir := "blocks"
-	blocks := make(map[string]string)
-	blockOrder := make([]string, 0)
+// getBlocksSubModel reads all .tpl files from blocksDir and orders them by their references to each other.
+// A reference cycle is an error that lists the whole path, e.g. header -> nav -> header.
+func getBlocksSubModel(blocksDir string) (*BlockSet, error) {
+	blocks := &BlockSet{
+		Data:      make(map[string]string),
+		templates: make(map[string]*Template),
+		static:    make(map[string]bool),
+		deps:      make(map[string][]string),
+	}
+	blockNames := make([]string, 0)
 
 	// Read all .tpl files in blocksDir
 	dirEntries, err := os.ReadDir(blocksDir)
@@ -272,90 +284,256 @@ func getBlocksSubModel() (map[string]string, error) {
 		}
 		name := entry.Name()
 		if strings.HasSuffix(name, ".tpl") {
-			/*
-			fmt.Printf("Processing block: %s\n", name);
-			*/
 			blockName := strings.TrimSuffix(name, ".tpl")
 			content, err := os.ReadFile(filepath.Join(blocksDir, name))
 			if err != nil {
 				return nil, err
 			}
-			blocks[blockName] = string(content)
-			blockOrder = append(blockOrder, blockName)
+			tpl, err := compileTemplate("blocks/"+blockName, string(content))
+			if err != nil {
+				return nil, err
+			}
+			if tpl.extends != "" {
+				return nil, fmt.Errorf("блок %s: {extends} в блоках не поддерживается", blockName)
+			}
+			blocks.Data[blockName] = string(content)
+			blocks.templates[blockName] = tpl
+			blockNames = append(blockNames, blockName)
 		}
 	}
-
-	// Check for self-references
-	for blockName, content := range blocks {
-		if strings.Contains(content, "{"+blockName+"}") {
-			return nil, fmt.Errorf("block '%s' contains a forbidden self-reference", blockName)
+	sort.Strings(blockNames)
+
+	// Order blocks depth-first so that dependencies are expanded before the blocks using them
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != name {
+				start++
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("циклическая ссылка между блоками: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+		static := true
+		for _, ref := range blocks.templates[name].refs {
+			if _, isBlock := blocks.templates[ref]; isBlock {
+				if err := visit(ref); err != nil {
+					return err
+				}
+				blocks.deps[name] = append(blocks.deps[name], ref)
+				static = static && blocks.static[ref]
+			} else if !strings.HasPrefix(ref, "site.") {
+				static = false
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		blocks.static[name] = static
+		blocks.order = append(blocks.order, name)
+		return nil
+	}
+	for _, name := range blockNames {
+		if err := visit(name); err != nil {
+			return nil, err
 		}
 	}
+	return blocks, nil
+}
 
-	/*
-	// Substitute {blockname} for other blocks using strings.Replace
-	for _, blockName := range blockOrder {
-		for _, otherName := range blockOrder {
-			if blockName == otherName {
-				continue
+// expand renders blocks into data in dependency order, so blocks may use other blocks and page attributes.
+// Static blocks are rendered once per build and reused. Rendered blocks are trusted HTML and are marked in safe.
+func (bs *BlockSet) expand(data map[string]string, safe map[string]bool) error {
+	bs.staticOnce.Do(bs.renderStatic)
+	if bs.staticErr != nil {
+		return bs.staticErr
+	}
+	for _, name := range bs.order {
+		value, cached := bs.staticRendered[name]
+		if !cached {
+			var err error
+			if value, err = bs.templates[name].Execute(data, safe, bs.assets); err != nil {
+				return err
 			}
-			blocks[blockName] = strings.ReplaceAll(blocks[blockName], "{"+otherName+"}", blocks[otherName])
 		}
+		data[name] = value
+		safe[name] = true
 	}
-	*/
-	// Print all block names and their values to the terminal
-	/*
-	for k, v := range blocks {
-		fmt.Printf("Block: %s\nValue:\n%s\n---\n", k, v)
+	return nil
+}
+
+// renderStatic renders blocks that do not depend on page attributes
+func (bs *BlockSet) renderStatic() {
+	data := make(map[string]string, len(bs.Data))
+	for k, v := range bs.Data {
+		data[k] = v
+	}
+	safe := make(map[string]bool)
+	bs.staticRendered = make(map[string]string)
+	for _, name := range bs.order {
+		if !bs.static[name] {
+			continue
+		}
+		value, err := bs.templates[name].Execute(data, safe, bs.assets)
+		if err != nil {
+			bs.staticErr = err
+			return
+		}
+		data[name] = value
+		safe[name] = true
+		bs.staticRendered[name] = value
 	}
-	*/
-	return blocks, nil
 }
 
 // WriteTask is used to send output path and data to writing goroutines
 type WriteTask struct {
-	Path string
-	Data []byte
+	Path   string
+	Data   []byte
+	PageID string
+	Entry  *ManifestPage // Запись манифеста, которая сохраняется после успешной записи
 }
 
-func main() {
-	start := time.Now()
-	// Проверяем наличие необходимых директорий
-	if _, err := os.Stat("templates"); os.IsNotExist(err) {
-		fmt.Println(msgTemplatesDirNotFound)
-		return
+// listPages рекурсивно находит страницы внутри contentDir.
+// Директория с файлами *.val или template.setting является страницей, её файлы *.md — атрибутами.
+// В остальных директориях файлы *.md являются однофайловыми страницами.
+// Вложенные директории просматриваются в обоих случаях.
+func listPages(contentDir string) ([]string, error) {
+	pages := make([]string, 0)
+	if err := collectPages(contentDir, true, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// collectPages добавляет в pages страницы из директории dir и её поддиректорий
+func collectPages(dir string, root bool, pages *[]string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	isPage := false
+	if !root {
+		for _, entry := range entries {
+			if !entry.IsDir() && (filepath.Ext(entry.Name()) == ".val" || entry.Name() == "template.setting") {
+				isPage = true
+				break
+			}
+		}
 	}
+	if isPage {
+		*pages = append(*pages, dir)
+	}
+
+	pageDirs := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		before := len(*pages)
+		subDir := filepath.Join(dir, entry.Name())
+		if err := collectPages(subDir, false, pages); err != nil {
+			return err
+		}
+		if len(*pages) > before && (*pages)[before] == subDir {
+			pageDirs[entry.Name()] = true
+		}
+	}
+
+	if isPage {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".md")
+		if pageDirs[name] {
+			return fmt.Errorf(msgErrorDuplicatePage, name, filepath.Join(dir, name), filepath.Join(dir, entry.Name()))
+		}
+		*pages = append(*pages, filepath.Join(dir, entry.Name()))
+	}
+	return nil
+}
 
-	if _, err := os.Stat("content"); os.IsNotExist(err) {
-		fmt.Println(msgContentDirNotFound)
-		return
+// preparePage решает, попадает ли страница в сборку, и назначает ей путь результата и шаблон
+// по умолчанию. Возвращает false для черновиков, отложенных и устаревших страниц. Путь, уже
+// занятый другой страницей в outputs (путь -> идентификатор), возвращается как ошибка.
+func preparePage(model *Model, opts *Options, now time.Time, outputs *sync.Map) (bool, error) {
+	if !opts.published(model, now) {
+		return false, nil
+	}
+	if err := assignOutputPath(model, opts.Config.Build.PrettyURLs); err != nil {
+		return true, err
+	}
+	if other, exists := outputs.LoadOrStore(model.Path, model.ID); exists {
+		return true, fmt.Errorf(msgErrorOutputConflict, other, model.ID, model.Path)
 	}
+	if model.Template == "" {
+		model.Template = opts.Config.Build.DefaultTemplate
+	}
+	return true, nil
+}
 
-	// Создаём директорию build, если она не существует
-	if _, err := os.Stat("build"); os.IsNotExist(err) {
-		os.Mkdir("build", 0755)
+// runBuild генерирует сайт из директорий, заданных в opts, и возвращает отчёт о сборке.
+// Если передан cache, неизменившиеся страницы не читаются с диска заново.
+func runBuild(opts *Options, cache *pageCache) *BuildReport {
+	start := time.Now()
+	report := newBuildReport(opts.stopOnError())
+	defer report.finish(start)
+
+	// Создаём директорию сборки, если она не существует
+	if _, err := os.Stat(opts.OutputDir); os.IsNotExist(err) {
+		os.MkdirAll(opts.OutputDir, 0755)
 	}
 
 	// Получаем блоки
-	blocks, err := getBlocksSubModel()
+	blocks, err := getBlocksSubModel(opts.BlocksDir)
+	if err != nil {
+		report.addError(stageBlocks, opts.BlocksDir, err)
+		return report
+	}
+	// Переменные сайта из конфигурации доступны всем шаблонам наравне с блоками
+	for k, v := range opts.Config.templateVars() {
+		blocks.Data[k] = v
+	}
+	// Адреса файлов static/ нужны шаблонам до рендеринга, поэтому отпечатки вычисляются заранее
+	assets, err := newAssetIndex(opts)
 	if err != nil {
-		fmt.Printf("Ошибка при обработке блоков: %v\n", err)
-		return
+		report.addError(stageAsset, opts.StaticDir, err)
+		return report
 	}
+	blocks.assets = assets
 
 	// Обрабатываем все страницы параллельно
-	pageDirs, err := ioutil.ReadDir("content")
+	pages, err := listPages(opts.ContentDir)
 	if err != nil {
-		fmt.Printf(msgErrorReadingContent, err)
-		return
+		report.addError(stageContent, opts.ContentDir, err)
+		return report
 	}
+	report.Pages = len(pages)
+	templates := newTemplateSet(opts.TemplatesDir)
 
-	// Prepare list of page paths
-	pages := make([]string, 0)
-	for _, pageDir := range pageDirs {
-		if pageDir.IsDir() {
-			pages = append(pages, filepath.Join("content", pageDir.Name()))
-		}
+	// Манифест предыдущей сборки позволяет не рендерить страницы, входные данные которых не изменились
+	settings := settingsFingerprint(opts)
+	prev := loadManifest(opts.OutputDir, settings)
+	if opts.Force {
+		prev.invalidate()
+	}
+	next := newManifest(settings)
+	if cache != nil {
+		cache.reset(settings + blocks.hash())
 	}
 
 	chModels := make(chan string, 10)
@@ -366,9 +544,9 @@ func main() {
 	var wgProcessors sync.WaitGroup
 	var wgWriters sync.WaitGroup
 
-	numReaders := 200
-	numProcessors := 2
-	numWriters := 200
+	numReaders := opts.Config.Build.Readers
+	numProcessors := opts.Config.Build.Processors
+	numWriters := opts.Config.Build.Writers
 
 	// Feed page paths to chModels in parallel
 	pagesCh := make(chan string, len(pages))
@@ -382,43 +560,87 @@ func main() {
 		go func() {
 			defer wgReaders.Done()
 			for pagePath := range pagesCh {
+				// В режиме -fail-fast после первой ошибки оставшиеся страницы не обрабатываются
+				if report.stopped() {
+					continue
+				}
 				chModels <- pagePath
 			}
 		}()
 	}
 
+	// Пути результатов всех страниц: две страницы не должны записываться в один файл
+	var outputs sync.Map
+	// Черновики, отложенные и устаревшие страницы не собираются, а их прежний результат удаляется
+	var excluded sync.Map
+	now := time.Now()
+
 	// Processors: read from chModels, process, send to chWriting, collect models
 	for i := 0; i < numProcessors; i++ {
 		wgProcessors.Add(1)
 		go func() {
 			defer wgProcessors.Done()
 			for pagePath := range chModels {
-				model, err := processPage(pagePath, blocks)
+				if report.stopped() {
+					continue
+				}
+				model, err := cache.load(opts.ContentDir, pagePath, blocks.Data, opts.Config.Build.MarkdownHTML)
+				if err != nil {
+					report.addError(stagePage, pageIDFromPath(opts.ContentDir, pagePath), err)
+					continue
+				}
+				included, err := preparePage(model, opts, now, &outputs)
+				if !included {
+					report.pageExcluded()
+					excluded.Store(model.ID, true)
+					continue
+				}
 				if err != nil {
-					fmt.Printf(msgErrorProcessingPage, filepath.Base(pagePath), err)
+					report.addError(stagePage, model.ID, err)
 					continue
 				}
 				if model.Template == "" {
-					fmt.Printf(msgWarningNoTemplate, model.ID)
+					report.addWarning(stageTemplate, model.ID, msgNoTemplate)
 					continue
 				}
-				templateContent, templateVars, err := loadTemplate(model.Template)
+				tpl, templateVars, err := templates.loadTemplate(model.Template)
 				if err != nil {
-					fmt.Printf(msgErrorLoadingTemplate, model.ID, err)
+					report.addError(stageTemplate, model.ID, err)
 					continue
 				}
+				entry := &ManifestPage{
+					Inputs:    pageInputs(model, blocks),
+					Template:  model.Template,
+					Templates: tpl.fingerprint(),
+					Blocks:    blocks.fingerprint(tpl),
+					Assets:    assets.inputs(tpl, blocks),
+					Output:    model.Path,
+				}
 				for k, v := range templateVars {
 					if _, exists := model.Data[k]; !exists {
 						model.Data[k] = v
 					}
 				}
-				output, err := renderTemplate(templateContent, model)
+				if prev.unchanged(model.ID, entry, opts.OutputDir) {
+					report.pageSkipped()
+					next.setPage(model.ID, entry)
+					chCollectedModels <- model
+					continue
+				}
+				if err := blocks.expand(model.Data, model.Safe); err != nil {
+					report.addError(stageRender, model.ID, err)
+					continue
+				}
+				output, err := renderTemplate(tpl, model, assets)
 				if err != nil {
-					fmt.Printf(msgErrorRendering, model.ID, err)
+					report.addError(stageRender, model.ID, err)
 					continue
 				}
-				outputPath := filepath.Join("build", model.ID+".html")
-				chWriting <- WriteTask{Path: outputPath, Data: []byte(output)}
+				if opts.minifies(tpl.Name) {
+					output = minifyHTML(output)
+				}
+				outputPath := filepath.Join(opts.OutputDir, filepath.FromSlash(model.Path))
+				chWriting <- WriteTask{Path: outputPath, Data: output, PageID: model.ID, Entry: entry}
 				chCollectedModels <- model
 			}
 		}()
@@ -430,11 +652,17 @@ func main() {
 		go func() {
 			defer wgWriters.Done()
 			for task := range chWriting {
+				if err := os.MkdirAll(filepath.Dir(task.Path), 0755); err != nil {
+					report.addError(stageWrite, task.Path, err)
+					continue
+				}
 				err := ioutil.WriteFile(task.Path, task.Data, 0644)
 				if err != nil {
-					fmt.Printf(msgErrorWritingOutput, task.Path, err)
+					report.addError(stageWrite, task.Path, err)
 					continue
 				}
+				report.pageGenerated()
+				next.setPage(task.PageID, task.Entry)
 				/*
 				fmt.Printf(msgGenerated, task.Path)
 				*/
@@ -449,25 +677,51 @@ func main() {
 	wgProcessors.Wait()
 	close(chWriting)
 
-	// Collect all models from chCollectedModels
+	// Collect all models from chCollectedModels.
+	// Страницы с ошибками модель не отправляют, поэтому читаем канал до закрытия.
+	close(chCollectedModels)
 	var models []*Model
-	for i := 0; i < len(pages); i++ {
-		model := <-chCollectedModels
-		if model != nil {
-			models = append(models, model)
-		}
+	for model := range chCollectedModels {
+		models = append(models, model)
 	}
-	close(chCollectedModels)
+	// Порядок моделей не зависит от воркеров, поэтому JSON категорий стабилен между сборками
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
 
 	// Wait for all writers to finish
 	wgWriters.Wait()
 
-	// Generate category files (single-threaded, as before)
-	if err := generateCategoryFiles(models, blocks); err != nil {
-		fmt.Printf("Ошибка при генерации файлов категорий: %v\n", err)
+	// Страницы с ошибками и необработанные после -fail-fast сохраняют прежнюю запись манифеста:
+	// их результат не удаляется, а при следующем запуске они собираются заново
+	for _, pagePath := range pages {
+		id := pageIDFromPath(opts.ContentDir, pagePath)
+		if _, skip := excluded.Load(id); skip {
+			continue
+		}
+		if next.page(id) == nil {
+			if entry := prev.page(id); entry != nil {
+				next.setPage(id, entry)
+			}
+		}
+	}
+	for _, err := range prev.removeStale(next, opts.OutputDir) {
+		report.addError(stageWrite, opts.OutputDir, err)
+	}
+
+	if !report.stopped() {
+		owners := outputOwners(next)
+		copyAssets(models, assets, opts, report, prev, next, owners)
+		terms := generateTaxonomies(models, blocks, opts, report, prev, next, owners)
+		generateSitemap(models, terms, opts, report, prev, next, owners)
+		for _, err := range prev.removeStaleCategories(next, opts.OutputDir) {
+			report.addError(stageCategory, opts.OutputDir, err)
+		}
+	}
+	if err := next.save(opts.OutputDir); err != nil {
+		report.addError(stageWrite, manifestFile, err)
 	}
+	return report
+}
 
-	fmt.Println(msgSiteGenerationDone)
-	elapsed := time.Since(start)
-	fmt.Printf("Время выполнения: %d мс\n", elapsed.Milliseconds())
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
@@ -0,0 +1,185 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты поиска страниц, путей результата и раскрытия глобальных блоков.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListPages(t *testing.T) {
+	contentDir := t.TempDir()
+	for _, name := range []string{
+		"index.md",
+		"about/title.val",
+		"about/bio.md",         // Атрибут страницы-директории, а не страница
+		"about/team/title.val", // Страница внутри страницы
+		"docs/intro.md",        // docs — не страница, но её файлы *.md — страницы
+		"docs/setup/template.setting",
+		"docs/setup/notes.txt",
+		"blog/2025/post.md",
+		"blog/2025/post/photo.jpg", // Директория без атрибутов не конфликтует с post.md
+		".drafts/hidden.md",
+		"empty/readme.txt",
+	} {
+		path := filepath.Join(contentDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pages, err := listPages(contentDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, page := range pages {
+		rel, err := filepath.Rel(contentDir, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{"about", "about/team", "blog/2025/post.md", "docs/setup", "docs/intro.md", "index.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %v, ожидалось %v", got, want)
+	}
+
+	// Директория и файл .md с одним именем дают один и тот же идентификатор страницы
+	newsDir := filepath.Join(contentDir, "blog", "news")
+	if err := os.MkdirAll(newsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(newsDir, "title.val"), newsDir + ".md"} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = listPages(contentDir)
+	if want := fmt.Sprintf(msgErrorDuplicatePage, "news", newsDir, newsDir+".md"); err == nil || err.Error() != want {
+		t.Errorf("ошибка %v, ожидалась %q", err, want)
+	}
+}
+
+func TestAssignOutputPath(t *testing.T) {
+	tests := []struct {
+		id     string
+		slug   string
+		pretty bool
+		path   string
+		url    string
+		err    bool
+	}{
+		{"about", "", false, "about.html", "/about.html", false},
+		{"about", "", true, "about/index.html", "/about/", false},
+		{"docs/setup", "", false, "docs/setup.html", "/docs/setup.html", false},
+		{"docs/setup", "", true, "docs/setup/index.html", "/docs/setup/", false},
+		{"index", "", false, "index.html", "/", false},
+		{"index", "", true, "index.html", "/", false},
+		{"docs/index", "", true, "docs/index.html", "/docs/", false},
+		{"blog/2025-07-02-post", "hello", false, "blog/hello.html", "/blog/hello.html", false},
+		{"blog/2025-07-02-post", " hello ", true, "blog/hello/index.html", "/blog/hello/", false},
+		{"blog/home", "index", false, "blog/index.html", "/blog/", false},
+		{"about", "a/b", false, "", "", true},
+		{"about", `a\b`, false, "", "", true},
+		{"about", "..", false, "", "", true},
+		{"about", ".", true, "", "", true},
+	}
+	for _, tt := range tests {
+		model := &Model{ID: tt.id, Data: map[string]string{}}
+		if tt.slug != "" {
+			model.Data["slug"] = tt.slug
+		}
+		err := assignOutputPath(model, tt.pretty)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s (slug %q): ошибки нет, путь %q", tt.id, tt.slug, model.Path)
+			}
+			continue
+		}
+		if err != nil || model.Path != tt.path || model.URL != tt.url {
+			t.Errorf("%s (slug %q, prettyURLs %v): путь %q, адрес %q, ошибка %v; ожидалось %q и %q",
+				tt.id, tt.slug, tt.pretty, model.Path, model.URL, err, tt.path, tt.url)
+		}
+	}
+}
+
+// writeBlocks создаёт директорию блоков с файлами <имя>.tpl
+func writeBlocks(t *testing.T, blocks map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range blocks {
+		if err := os.WriteFile(filepath.Join(dir, name+".tpl"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBlockSetExpandsInDependencyOrder(t *testing.T) {
+	dir := writeBlocks(t, map[string]string{
+		"header": "<header>{nav}</header>",
+		"nav":    "<nav>{site.title} / {title}</nav>",
+		"footer": "<footer>{site.title}</footer>",
+		"page":   "{header}{footer}",
+	})
+	blocks, err := getBlocksSubModel(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"footer", "nav", "header", "page"}; !reflect.DeepEqual(blocks.order, want) {
+		t.Errorf("порядок %v, ожидался %v", blocks.order, want)
+	}
+	if !blocks.static["footer"] || blocks.static["nav"] || blocks.static["header"] {
+		t.Errorf("статические блоки определены неверно: %v", blocks.static)
+	}
+
+	// Переменные сайта добавляются к блокам, как при сборке
+	blocks.Data["site.title"] = "S"
+	data := map[string]string{"title": "<T>"}
+	for k, v := range blocks.Data {
+		data[k] = v
+	}
+	safe := make(map[string]bool)
+	if err := blocks.expand(data, safe); err != nil {
+		t.Fatal(err)
+	}
+	want := "<header><nav>S / &lt;T&gt;</nav></header><footer>S</footer>"
+	if data["page"] != want {
+		t.Errorf("получено %q, ожидалось %q", data["page"], want)
+	}
+	if !safe["header"] || !safe["page"] {
+		t.Errorf("раскрытые блоки не отмечены доверенными: %v", safe)
+	}
+}
+
+func TestBlockSetReportsCyclePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks map[string]string
+		want   string
+	}{
+		{"цепочка", map[string]string{"a": "{b}", "b": "{c}", "c": "{a}"}, "циклическая ссылка между блоками: a -> b -> c -> a"},
+		{"ссылка на себя", map[string]string{"a": "<p>{a}</p>"}, "циклическая ссылка между блоками: a -> a"},
+		{"цикл вне начала обхода", map[string]string{"a": "{b}", "b": "{c}", "c": "{b}"}, "циклическая ссылка между блоками: b -> c -> b"},
+		{"ссылка внутри условия", map[string]string{"a": "{if x}{b}{end}", "b": "{each list}{a}{end}"}, "a -> b -> a"},
+	}
+	for _, tt := range tests {
+		_, err := getBlocksSubModel(writeBlocks(t, tt.blocks))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -15,6 +15,10 @@ const (
 
 	templateVal = "blog"
 	categoryVal = "main"
+
+	// singleFilePages включает генерацию однофайловых страниц <id>.md с front matter
+	// вместо директорий с отдельным файлом на каждый атрибут
+	singleFilePages = false
 )
 
 var (
@@ -54,6 +58,13 @@ func main() {
 	}
 	for i := 0; i < nPages; i++ {
 		pageID := randomID(8)
+		if singleFilePages {
+			page := fmt.Sprintf("---\ntitle: %q\ntemplate: %s\ncategory: %s\n---\n%s\n", randomTitle(), templateVal, categoryVal, randomContent())
+			if err := os.WriteFile(filepath.Join(dirContent, pageID+".md"), []byte(page), 0644); err != nil {
+				fmt.Printf("Ошибка при записи %s.md: %v\n", pageID, err)
+			}
+			continue
+		}
 		pageDir := filepath.Join(dirContent, pageID)
 		if err := os.Mkdir(pageDir, 0755); err != nil {
 			fmt.Printf("Ошибка при создании директории %s: %v\n", pageDir, err)
@@ -0,0 +1,383 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Манифест сборки для инкрементальной пересборки изменившихся страниц.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// manifestFile — имя манифеста в директории сборки
+const manifestFile = ".goferret-manifest.json"
+
+// manifestVersion меняется, когда меняется формат манифеста или результат рендеринга
+const manifestVersion = 2
+
+// BuildManifest хранит входные данные каждой страницы и категории предыдущей сборки.
+// Страница рендерится заново, только если изменились её атрибуты, шаблоны, блоки или путь результата.
+type BuildManifest struct {
+	Version    int                          `json:"version"`
+	Settings   string                       `json:"settings"` // Отпечаток конфигурации сайта
+	Pages      map[string]*ManifestPage     `json:"pages"`
+	Categories map[string]*ManifestCategory `json:"categories"`
+	Assets     map[string]string            `json:"assets"` // Скопированный файл -> исходный
+
+	mu    sync.Mutex
+	stale bool // Манифест сброшен: скопированные файлы тоже записываются заново
+}
+
+// ManifestPage описывает входные данные и результат одной страницы
+type ManifestPage struct {
+	Inputs    map[string]string `json:"inputs"` // Атрибут страницы -> отпечаток значения
+	Template  string            `json:"template"`
+	Templates map[string]string `json:"templates"` // Шаблоны цепочки {extends} -> отпечаток файла
+	Blocks    map[string]string `json:"blocks"`    // Используемые блоки -> отпечаток исходника
+	Assets    map[string]string `json:"assets"`    // Ссылки {asset} и {integrity} -> итоговое значение
+	Output    string            `json:"output"`
+}
+
+// ManifestCategory описывает страницы списка категории
+type ManifestCategory struct {
+	Fingerprint string   `json:"fingerprint"` // Отпечаток элементов, шаблона и блоков
+	Outputs     []string `json:"outputs"`
+}
+
+// newManifest создаёт пустой манифест для сборки с настройками settings
+func newManifest(settings string) *BuildManifest {
+	return &BuildManifest{
+		Version:    manifestVersion,
+		Settings:   settings,
+		Pages:      make(map[string]*ManifestPage),
+		Categories: make(map[string]*ManifestCategory),
+		Assets:     make(map[string]string),
+	}
+}
+
+// loadManifest читает манифест предыдущей сборки из outputDir. Если манифеста нет или он повреждён,
+// возвращается пустой манифест. Манифест сборки с другими настройками сбрасывается через invalidate.
+func loadManifest(outputDir, settings string) *BuildManifest {
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, manifestFile))
+	if err != nil {
+		return newManifest(settings)
+	}
+	m := newManifest(settings)
+	if err := json.Unmarshal(data, m); err != nil || m.Version != manifestVersion {
+		return newManifest(settings)
+	}
+	if m.Pages == nil {
+		m.Pages = make(map[string]*ManifestPage)
+	}
+	if m.Categories == nil {
+		m.Categories = make(map[string]*ManifestCategory)
+	}
+	if m.Assets == nil {
+		m.Assets = make(map[string]string)
+	}
+	if m.Settings != settings {
+		m.invalidate()
+	}
+	return m
+}
+
+// invalidate заставляет собрать все страницы и категории заново. Пути результатов сохраняются,
+// чтобы файлы, которых не будет в новой сборке, всё равно были удалены.
+func (m *BuildManifest) invalidate() {
+	m.stale = true
+	for _, entry := range m.Pages {
+		entry.Inputs = nil
+	}
+	for _, entry := range m.Categories {
+		entry.Fingerprint = ""
+	}
+}
+
+// save записывает манифест в outputDir
+func (m *BuildManifest) save(outputDir string) error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(outputDir, manifestFile), data, 0644)
+}
+
+// page возвращает запись страницы id или nil
+func (m *BuildManifest) page(id string) *ManifestPage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Pages[id]
+}
+
+// setPage сохраняет запись страницы id
+func (m *BuildManifest) setPage(id string, entry *ManifestPage) {
+	m.mu.Lock()
+	m.Pages[id] = entry
+	m.mu.Unlock()
+}
+
+// setCategory сохраняет запись категории
+func (m *BuildManifest) setCategory(category string, entry *ManifestCategory) {
+	m.mu.Lock()
+	m.Categories[category] = entry
+	m.mu.Unlock()
+}
+
+// setAsset сохраняет запись скопированного файла
+func (m *BuildManifest) setAsset(output, source string) {
+	m.mu.Lock()
+	m.Assets[output] = source
+	m.mu.Unlock()
+}
+
+// unchanged сообщает, что страница id собрана из тех же входных данных и её результат на месте
+func (m *BuildManifest) unchanged(id string, entry *ManifestPage, outputDir string) bool {
+	prev := m.page(id)
+	if prev == nil || prev.Template != entry.Template || prev.Output != entry.Output ||
+		!equalFingerprints(prev.Inputs, entry.Inputs) ||
+		!equalFingerprints(prev.Templates, entry.Templates) ||
+		!equalFingerprints(prev.Blocks, entry.Blocks) ||
+		!equalFingerprints(prev.Assets, entry.Assets) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(entry.Output)))
+	return err == nil
+}
+
+// removeStale удаляет результаты страниц, которых больше нет в next, и пустые директории после них
+func (m *BuildManifest) removeStale(next *BuildManifest, outputDir string) []error {
+	used := make(map[string]bool, len(next.Pages))
+	for _, entry := range next.Pages {
+		used[entry.Output] = true
+	}
+	var errs []error
+	for _, entry := range m.Pages {
+		if used[entry.Output] {
+			continue
+		}
+		if err := removeOutput(outputDir, entry.Output); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// removeStaleCategories удаляет файлы категорий, которых нет в next: исчезнувших категорий
+// и страниц списка, ставших лишними после уменьшения категории. Файлы, которые теперь
+// копируются из static/ или записываются страницами, не удаляются.
+func (m *BuildManifest) removeStaleCategories(next *BuildManifest, outputDir string) []error {
+	used := make(map[string]bool)
+	for _, entry := range next.Categories {
+		for _, output := range entry.Outputs {
+			used[output] = true
+		}
+	}
+	for output := range next.Assets {
+		used[output] = true
+	}
+	for _, entry := range next.Pages {
+		used[entry.Output] = true
+	}
+	var errs []error
+	for _, entry := range m.Categories {
+		for _, output := range entry.Outputs {
+			if used[output] {
+				continue
+			}
+			if err := removeOutput(outputDir, output); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// removeStaleAssets удаляет копии файлов, которых нет в next, если их путь не занят страницей
+func (m *BuildManifest) removeStaleAssets(next *BuildManifest, outputDir string) []error {
+	pages := make(map[string]bool, len(next.Pages))
+	for _, entry := range next.Pages {
+		pages[entry.Output] = true
+	}
+	var errs []error
+	for output := range m.Assets {
+		if _, ok := next.Assets[output]; ok || pages[output] {
+			continue
+		}
+		if err := removeOutput(outputDir, output); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// outputOwners возвращает владельцев путей результата, начиная со страниц next. Файлы static/
+// и термины таксономий добавляют в него свои пути и не перезаписывают уже занятые.
+func outputOwners(next *BuildManifest) map[string]string {
+	owners := make(map[string]string, len(next.Pages))
+	for id, entry := range next.Pages {
+		owners[entry.Output] = "страница " + id
+	}
+	return owners
+}
+
+// outputsExist сообщает, что все файлы outputs есть в outputDir
+func outputsExist(outputDir string, outputs []string) bool {
+	for _, output := range outputs {
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(output))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// removeOutput удаляет файл результата и опустевшие родительские директории внутри outputDir.
+// Пути берутся из манифеста на диске, поэтому путь вне outputDir (../, абсолютный) пропускается.
+func removeOutput(outputDir, output string) error {
+	if !isOutputPath(output) {
+		return nil
+	}
+	path := filepath.Join(outputDir, filepath.FromSlash(output))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	root := filepath.Clean(outputDir)
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// isOutputPath проверяет, что путь результата из манифеста указывает внутрь директории сборки
+func isOutputPath(output string) bool {
+	return isAssetName(output) && validatePageID(output) == nil
+}
+
+// equalFingerprints сравнивает два набора отпечатков
+func equalFingerprints(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// contentHash возвращает короткий отпечаток строки
+func contentHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:12])
+}
+
+// settingsFingerprint возвращает отпечаток конфигурации и шаблонов коллекций: их изменение
+// пересобирает весь сайт. Пути директорий не учитываются, поэтому сборка из другого -root
+// с теми же исходниками остаётся инкрементальной.
+func settingsFingerprint(opts *Options) string {
+	data, err := json.Marshal(opts.Config)
+	if err != nil {
+		return ""
+	}
+	return contentHash(fmt.Sprintf("%d\x00%s\x00%s", manifestVersion, data, collectionsFingerprint(opts.CollectionsDir)))
+}
+
+// collectionsFingerprint возвращает отпечаток имён и содержимого шаблонов *.tpl в директории dir
+func collectionsFingerprint(dir string) string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tpl" {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			// Нечитаемый шаблон всё равно меняет отпечаток, а ошибку сообщит сборка коллекции
+			data = []byte(err.Error())
+		}
+		sb.WriteString(entry.Name() + "\x00" + contentHash(string(data)) + "\x00")
+	}
+	return contentHash(sb.String())
+}
+
+// pageInputs возвращает отпечатки собственных атрибутов страницы, её шаблона и категории.
+// Блоки и переменные сайта учитываются отдельно.
+func pageInputs(model *Model, blocks *BlockSet) map[string]string {
+	inputs := make(map[string]string, len(model.Data)+2)
+	for k, v := range model.Data {
+		if _, shared := blocks.Data[k]; !shared {
+			inputs[k] = contentHash(v)
+		}
+	}
+	inputs[".template"] = contentHash(model.Template)
+	inputs[".category"] = contentHash(model.Category)
+	return inputs
+}
+
+// fingerprint возвращает отпечатки файлов всей цепочки наследования шаблона
+func (t *Template) fingerprint() map[string]string {
+	files := make(map[string]string)
+	for tpl := t; tpl != nil; tpl = tpl.parent {
+		files[tpl.Name] = tpl.hash
+	}
+	return files
+}
+
+// fingerprint возвращает отпечатки блоков, которые использует шаблон, с учётом вложенных блоков
+func (bs *BlockSet) fingerprint(t *Template) map[string]string {
+	used := make(map[string]string)
+	var use func(ref string)
+	use = func(ref string) {
+		if _, isBlock := bs.templates[ref]; !isBlock {
+			return
+		}
+		if _, seen := used[ref]; seen {
+			return
+		}
+		used[ref] = bs.templates[ref].hash
+		for _, dep := range bs.deps[ref] {
+			use(dep)
+		}
+	}
+	for tpl := t; tpl != nil; tpl = tpl.parent {
+		for _, ref := range tpl.refs {
+			use(ref)
+		}
+	}
+	return used
+}
+
+// hash возвращает отпечаток всех блоков для шаблона категорий, который получает их все
+func (bs *BlockSet) hash() string {
+	names := make([]string, 0, len(bs.templates))
+	for name := range bs.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteByte(0)
+		sb.WriteString(bs.templates[name].hash)
+		sb.WriteByte(0)
+	}
+	return contentHash(sb.String())
+}
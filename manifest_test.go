@@ -0,0 +1,183 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты инкрементальной сборки по манифесту.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIncrementalBuild(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("templates/page.tpl", "<h1>{title}</h1>{footer}")
+	write("templates/plain.tpl", "<p>{title}</p>")
+	write("blocks/footer.tpl", "<footer>{site.title}</footer>")
+	write("content/a/title.val", "A")
+	write("content/a/template.setting", "page")
+	write("content/b/title.val", "B")
+	write("content/b/template.setting", "page")
+	write("content/c/title.val", "C")
+	write("content/c/template.setting", "plain")
+	os.MkdirAll(filepath.Join(root, "collections"), 0755)
+
+	opts := &Options{
+		Root:           root,
+		TemplatesDir:   filepath.Join(root, "templates"),
+		ContentDir:     filepath.Join(root, "content"),
+		BlocksDir:      filepath.Join(root, "blocks"),
+		CollectionsDir: filepath.Join(root, "collections"),
+		StaticDir:      filepath.Join(root, "static"),
+		OutputDir:      filepath.Join(root, "build"),
+		Config:         defaultConfig(),
+		KeepGoing:      true,
+	}
+	opts.Config.Site["title"] = "S"
+
+	steps := []struct {
+		name      string
+		change    func()
+		generated int
+		skipped   int
+	}{
+		{"первая сборка", func() {}, 3, 0},
+		{"без изменений", func() {}, 0, 3},
+		{"изменён атрибут страницы", func() { write("content/a/title.val", "A2") }, 1, 2},
+		{"изменён блок", func() { write("blocks/footer.tpl", "<footer>© {site.title}</footer>") }, 2, 1},
+		{"изменён шаблон", func() { write("templates/plain.tpl", "<p>{title}!</p>") }, 1, 2},
+		{"изменена конфигурация", func() { opts.Config.Site["title"] = "S2" }, 3, 0},
+		{"принудительная сборка", func() { opts.Force = true }, 3, 0},
+		{"удалена страница", func() {
+			opts.Force = false
+			if err := os.RemoveAll(filepath.Join(root, "content", "b")); err != nil {
+				t.Fatal(err)
+			}
+		}, 0, 2},
+	}
+	for _, step := range steps {
+		step.change()
+		report := runBuild(opts, nil)
+		if report.Failed() {
+			t.Fatalf("%s: ошибки %+v", step.name, report.Errors)
+		}
+		if report.Generated != step.generated || report.Skipped != step.skipped {
+			t.Errorf("%s: собрано %d, пропущено %d; ожидалось %d и %d",
+				step.name, report.Generated, report.Skipped, step.generated, step.skipped)
+		}
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(opts.OutputDir, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		return string(data)
+	}
+	if got, want := read("a.html"), "<h1>A2</h1><footer>© S2</footer>"; got != want {
+		t.Errorf("a.html: получено %q, ожидалось %q", got, want)
+	}
+	if got, want := read("c.html"), "<p>C!</p>"; got != want {
+		t.Errorf("c.html: получено %q, ожидалось %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(opts.OutputDir, "b.html")); !os.IsNotExist(err) {
+		t.Errorf("результат удалённой страницы не удалён: %v", err)
+	}
+}
+
+func TestSettingsFingerprint(t *testing.T) {
+	newSite := func() *Options {
+		root := t.TempDir()
+		opts := &Options{Root: root, CollectionsDir: filepath.Join(root, "collections"), Config: defaultConfig()}
+		if err := os.MkdirAll(opts.CollectionsDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(opts.CollectionsDir, "category.tpl"), []byte("<h1>{category}</h1>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return opts
+	}
+	a, b := newSite(), newSite()
+	base := settingsFingerprint(a)
+	if got := settingsFingerprint(b); got != base {
+		t.Errorf("те же исходники в другом -root: отпечаток %s, ожидался %s", got, base)
+	}
+
+	steps := []struct {
+		name   string
+		change func()
+	}{
+		{"изменён шаблон коллекции", func() {
+			os.WriteFile(filepath.Join(b.CollectionsDir, "category.tpl"), []byte("<h2>{category}</h2>"), 0644)
+		}},
+		{"добавлен шаблон коллекции", func() {
+			os.WriteFile(filepath.Join(b.CollectionsDir, "terms.tpl"), []byte("{each terms}{name}{end}"), 0644)
+		}},
+		{"изменена конфигурация", func() { b.Config.Site["title"] = "S" }},
+	}
+	seen := map[string]string{base: "исходный сайт"}
+	for _, step := range steps {
+		step.change()
+		got := settingsFingerprint(b)
+		if other, ok := seen[got]; ok {
+			t.Errorf("%s: отпечаток совпадает с «%s»", step.name, other)
+		}
+		seen[got] = step.name
+	}
+}
+
+func TestRemoveStaleSkipsUnsafePaths(t *testing.T) {
+	root := t.TempDir()
+	outputDir := filepath.Join(root, "build")
+	write := func(path string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outside := filepath.Join(root, "outside.html")
+	stale := filepath.Join(outputDir, "old", "page.html")
+	write(outside)
+	write(stale)
+
+	// Манифест на диске может быть изменён вручную или повреждён
+	prev := newManifest("")
+	for id, output := range map[string]string{"a": "../outside.html", "b": outside, "c": "old/page.html", "d": "old/../../outside.html"} {
+		prev.Pages[id] = &ManifestPage{Output: filepath.ToSlash(output)}
+	}
+	prev.Categories["tags/go"] = &ManifestCategory{Outputs: []string{"../outside.html", "", "."}}
+	prev.Assets["../outside.html"] = "static/x"
+	next := newManifest("")
+
+	var errs []error
+	errs = append(errs, prev.removeStale(next, outputDir)...)
+	errs = append(errs, prev.removeStaleCategories(next, outputDir)...)
+	errs = append(errs, prev.removeStaleAssets(next, outputDir)...)
+	if len(errs) > 0 {
+		t.Errorf("ошибки %v", errs)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("удалён файл вне директории сборки: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("устаревший результат не удалён: %v", err)
+	}
+	if _, err := os.Stat(outputDir); err != nil {
+		t.Errorf("директория сборки удалена: %v", err)
+	}
+}
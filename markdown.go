@@ -0,0 +1,1191 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Преобразование Markdown (CommonMark с таблицами, сносками и якорями заголовков) в HTML.
+*/
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Виды блоков Markdown
+const (
+	mdParagraph = iota
+	mdHeading
+	mdCode
+	mdRule
+	mdQuote
+	mdList
+	mdItem
+	mdHTML
+	mdTable
+)
+
+// mdBlock — узел блочной структуры документа
+type mdBlock struct {
+	kind     int
+	text     string // Текст абзаца или заголовка, содержимое кода или HTML
+	level    int    // Уровень заголовка
+	info     string // Язык блока кода
+	children []*mdBlock
+	ordered  bool
+	start    int
+	tight    bool
+	align    []string
+	header   []string
+	rows     [][]string
+}
+
+// mdLink — определение ссылки вида [label]: url "title"
+type mdLink struct {
+	url   string
+	title string
+}
+
+// markdown хранит состояние разбора одного документа
+type markdown struct {
+	refs          map[string]mdLink
+	footnotes     map[string][]*mdBlock
+	footnoteOrder []string
+	footnoteIndex map[string]int
+	headingIDs    map[string]int
+	rawHTML       bool // HTML из исходника выводится как есть; иначе экранируется как текст
+}
+
+var (
+	mdATXHeading   = regexp.MustCompile(`^(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdRulePattern  = regexp.MustCompile(`^(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdSetextH1     = regexp.MustCompile(`^=+[ \t]*$`)
+	mdSetextH2     = regexp.MustCompile(`^-+[ \t]*$`)
+	mdFenceOpen    = regexp.MustCompile("^(`{3,}|~{3,})[ \t]*([^`]*)$")
+	mdBulletItem   = regexp.MustCompile(`^([-+*])([ \t]+|$)`)
+	mdOrderedItem  = regexp.MustCompile(`^(\d{1,9})([.)])([ \t]+|$)`)
+	mdTableDelim   = regexp.MustCompile(`^\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdFootnoteDef  = regexp.MustCompile(`^\[\^([^\]\s]+)\]:[ \t]*(.*)$`)
+	mdLinkRefDef   = regexp.MustCompile(`^\[([^\]]+)\]:[ \t]*(<[^>]*>|\S+)(?:[ \t]+("[^"]*"|'[^']*'|\([^)]*\)))?[ \t]*$`)
+	mdHTMLBlock    = regexp.MustCompile(`^<(?:!--|/?([A-Za-z][A-Za-z0-9-]*)(?:[\s/>]|$))`)
+	mdHeadingID    = regexp.MustCompile(`[ \t]*\{#([A-Za-z0-9_:.-]+)\}[ \t]*$`)
+	mdInlineTag    = regexp.MustCompile(`^(?:<[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][\w.:-]*(?:\s*=\s*(?:[^\s"'=<>` + "`" + `]+|'[^']*'|"[^"]*"))?)*\s*/?>|</[A-Za-z][A-Za-z0-9-]*\s*>|<!--[\s\S]*?-->)`)
+	mdAutolink     = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	mdEmailLink    = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*)>`)
+	mdEntity       = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	mdFootnoteRef  = regexp.MustCompile(`^\[\^([^\]\s]+)\]`)
+	mdHTMLTagStrip = regexp.MustCompile(`<[^>]*>`)
+)
+
+// mdBlockTags — HTML-теги, которые начинают HTML-блок даже посреди абзаца
+var mdBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true,
+	"div": true, "dl": true, "fieldset": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "iframe": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"script": true, "section": true, "style": true, "table": true, "ul": true,
+}
+
+// renderMarkdown преобразует текст Markdown в HTML. HTML-блоки и теги из исходника выводятся как есть
+// только при rawHTML (build.markdownHTML), иначе они экранируются как обычный текст.
+// Адреса ссылок и изображений с небезопасной схемой, например javascript:, заменяются на #unsafe.
+func renderMarkdown(src string, rawHTML bool) string {
+	md := &markdown{
+		refs:          make(map[string]mdLink),
+		footnotes:     make(map[string][]*mdBlock),
+		footnoteIndex: make(map[string]int),
+		headingIDs:    make(map[string]int),
+		rawHTML:       rawHTML,
+	}
+
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		lines[i] = expandIndentTabs(line)
+	}
+
+	blocks := md.parseBlocks(lines)
+	var sb strings.Builder
+	md.renderBlocks(&sb, blocks, false)
+	md.renderFootnotes(&sb)
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// expandIndentTabs заменяет табуляции в начальном отступе строки пробелами (шаг 4)
+func expandIndentTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			sb.WriteByte(' ')
+			col++
+		case '\t':
+			n := 4 - col%4
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+		default:
+			sb.WriteString(line[i:])
+			return sb.String()
+		}
+	}
+	return sb.String()
+}
+
+func isBlankLine(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func leadingSpaces(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+// mdListMarker описывает маркер элемента списка
+type mdListMarker struct {
+	ordered bool
+	char    byte // Символ маркера: -, +, * или разделитель . )
+	start   int
+	indent  int // Отступ содержимого элемента от начала строки
+	empty   bool
+}
+
+// parseListMarker распознаёт маркер списка в начале строки с отступом не более трёх пробелов
+func parseListMarker(line string) (mdListMarker, bool) {
+	indent := leadingSpaces(line)
+	if indent > 3 {
+		return mdListMarker{}, false
+	}
+	rest := line[indent:]
+	var m mdListMarker
+	var markerLen, spaces int
+	if sub := mdBulletItem.FindStringSubmatch(rest); sub != nil {
+		m.char = sub[1][0]
+		markerLen, spaces = 1, len(sub[2])
+	} else if sub := mdOrderedItem.FindStringSubmatch(rest); sub != nil {
+		m.ordered = true
+		m.char = sub[2][0]
+		m.start, _ = strconv.Atoi(sub[1])
+		markerLen, spaces = len(sub[1])+1, len(sub[3])
+	} else {
+		return mdListMarker{}, false
+	}
+
+	m.empty = isBlankLine(rest[markerLen:])
+	if m.empty || spaces > 4 {
+		// Содержимое с большим отступом считается блоком кода внутри элемента
+		spaces = 1
+	}
+	m.indent = indent + markerLen + spaces
+	return m, true
+}
+
+// startsBlock сообщает, начинает ли строка новый блок, прерывающий абзац
+func (md *markdown) startsBlock(line string) bool {
+	if leadingSpaces(line) > 3 {
+		return false
+	}
+	trimmed := strings.TrimLeft(line, " ")
+	if mdATXHeading.MatchString(trimmed) || mdRulePattern.MatchString(trimmed) ||
+		mdFenceOpen.MatchString(trimmed) || strings.HasPrefix(trimmed, ">") {
+		return true
+	}
+	if m, ok := parseListMarker(line); ok && !m.empty && (!m.ordered || m.start == 1) {
+		return true
+	}
+	if sub := mdHTMLBlock.FindStringSubmatch(trimmed); sub != nil && md.rawHTML {
+		return sub[1] == "" || mdBlockTags[strings.ToLower(sub[1])]
+	}
+	return false
+}
+
+// parseBlocks разбирает строки документа или контейнера в последовательность блоков
+func (md *markdown) parseBlocks(lines []string) []*mdBlock {
+	var blocks []*mdBlock
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, &mdBlock{kind: mdParagraph, text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlankLine(line) {
+			flush()
+			i++
+			continue
+		}
+
+		indent := leadingSpaces(line)
+		if indent >= 4 {
+			if len(para) > 0 {
+				// Ленивое продолжение абзаца
+				para = append(para, strings.TrimLeft(line, " "))
+				i++
+				continue
+			}
+			block, next := md.parseIndentedCode(lines, i)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+		trimmed := line[indent:]
+
+		// Заголовок Setext: строка из = или - под абзацем
+		if len(para) > 0 && (mdSetextH1.MatchString(trimmed) || mdSetextH2.MatchString(trimmed)) {
+			level := 2
+			if trimmed[0] == '=' {
+				level = 1
+			}
+			blocks = append(blocks, &mdBlock{kind: mdHeading, level: level, text: strings.Join(para, "\n")})
+			para = nil
+			i++
+			continue
+		}
+
+		if sub := mdFenceOpen.FindStringSubmatch(trimmed); sub != nil {
+			flush()
+			block, next := md.parseFencedCode(lines, i, indent, sub[1], sub[2])
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		if sub := mdATXHeading.FindStringSubmatch(trimmed); sub != nil {
+			flush()
+			blocks = append(blocks, &mdBlock{kind: mdHeading, level: len(sub[1]), text: sub[2]})
+			i++
+			continue
+		}
+
+		if mdRulePattern.MatchString(trimmed) {
+			flush()
+			blocks = append(blocks, &mdBlock{kind: mdRule})
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			flush()
+			block, next := md.parseQuote(lines, i)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		if m, ok := parseListMarker(line); ok && (len(para) == 0 || (!m.empty && (!m.ordered || m.start == 1))) {
+			flush()
+			block, next := md.parseList(lines, i, m)
+			blocks = append(blocks, block)
+			i = next
+			continue
+		}
+
+		if sub := mdHTMLBlock.FindStringSubmatch(trimmed); sub != nil && md.rawHTML && (len(para) == 0 || sub[1] == "" || mdBlockTags[strings.ToLower(sub[1])]) {
+			flush()
+			start := i
+			for i < len(lines) && !isBlankLine(lines[i]) {
+				i++
+			}
+			blocks = append(blocks, &mdBlock{kind: mdHTML, text: strings.Join(lines[start:i], "\n")})
+			continue
+		}
+
+		if len(para) == 0 {
+			if strings.Contains(trimmed, "|") && i+1 < len(lines) && mdTableDelim.MatchString(strings.TrimSpace(lines[i+1])) {
+				if block, next, ok := md.parseTable(lines, i); ok {
+					blocks = append(blocks, block)
+					i = next
+					continue
+				}
+			}
+			if sub := mdFootnoteDef.FindStringSubmatch(trimmed); sub != nil {
+				i = md.parseFootnoteDef(lines, i, sub[1], sub[2])
+				continue
+			}
+			if sub := mdLinkRefDef.FindStringSubmatch(trimmed); sub != nil {
+				label := normalizeLinkLabel(sub[1])
+				if _, exists := md.refs[label]; !exists {
+					md.refs[label] = mdLink{url: unescapeMarkdown(strings.Trim(sub[2], "<>")), title: unescapeMarkdown(trimTitleQuotes(sub[3]))}
+				}
+				i++
+				continue
+			}
+		}
+
+		para = append(para, strings.TrimLeft(line, " "))
+		i++
+	}
+	flush()
+	return blocks
+}
+
+// parseIndentedCode разбирает блок кода с отступом в четыре пробела
+func (md *markdown) parseIndentedCode(lines []string, i int) (*mdBlock, int) {
+	var code []string
+	for i < len(lines) && (isBlankLine(lines[i]) || leadingSpaces(lines[i]) >= 4) {
+		if isBlankLine(lines[i]) {
+			code = append(code, "")
+		} else {
+			code = append(code, lines[i][4:])
+		}
+		i++
+	}
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+	return &mdBlock{kind: mdCode, text: strings.Join(code, "\n") + "\n"}, i
+}
+
+// parseFencedCode разбирает блок кода, ограниченный ``` или ~~~
+func (md *markdown) parseFencedCode(lines []string, i, indent int, fence, info string) (*mdBlock, int) {
+	var code []string
+	i++
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		if leadingSpaces(line) <= 3 && strings.HasPrefix(trimmed, fence[:1]) {
+			run := len(trimmed) - len(strings.TrimLeft(trimmed, fence[:1]))
+			if run >= len(fence) && isBlankLine(trimmed[run:]) {
+				i++
+				break
+			}
+		}
+		// Отступ открывающей строки снимается с каждой строки кода
+		strip := indent
+		if n := leadingSpaces(line); n < strip {
+			strip = n
+		}
+		code = append(code, line[strip:])
+	}
+	text := strings.Join(code, "\n")
+	if len(code) > 0 {
+		text += "\n"
+	}
+	lang := strings.Fields(unescapeMarkdown(info))
+	block := &mdBlock{kind: mdCode, text: text}
+	if len(lang) > 0 {
+		block.info = lang[0]
+	}
+	return block, i
+}
+
+// parseQuote разбирает цитату: строки с префиксом > и ленивые продолжения абзаца
+func (md *markdown) parseQuote(lines []string, i int) (*mdBlock, int) {
+	var inner []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		if leadingSpaces(line) <= 3 && strings.HasPrefix(trimmed, ">") {
+			rest := trimmed[1:]
+			if strings.HasPrefix(rest, " ") {
+				rest = rest[1:]
+			}
+			inner = append(inner, rest)
+			continue
+		}
+		if !isBlankLine(line) && len(inner) > 0 && !isBlankLine(inner[len(inner)-1]) && !md.startsBlock(line) {
+			inner = append(inner, line)
+			continue
+		}
+		break
+	}
+	return &mdBlock{kind: mdQuote, children: md.parseBlocks(inner)}, i
+}
+
+// parseList разбирает список, начинающийся со строки i с маркером first
+func (md *markdown) parseList(lines []string, i int, first mdListMarker) (*mdBlock, int) {
+	list := &mdBlock{kind: mdList, ordered: first.ordered, start: first.start, tight: true}
+	marker := first
+	item := []string{markerContent(lines[i], marker)}
+	sawBlank := false
+	var items [][]string
+
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if isBlankLine(line) {
+			item = append(item, "")
+			sawBlank = true
+			continue
+		}
+		indent := leadingSpaces(line)
+		if indent >= marker.indent {
+			if sawBlank && hasContent(item) {
+				list.tight = false
+			}
+			item = append(item, line[marker.indent:])
+			sawBlank = false
+			continue
+		}
+		if m, ok := parseListMarker(line); ok && m.ordered == first.ordered && m.char == first.char {
+			if sawBlank {
+				list.tight = false
+			}
+			items = append(items, item)
+			marker = m
+			item = []string{markerContent(line, m)}
+			sawBlank = false
+			continue
+		}
+		if !sawBlank && !md.startsBlock(line) {
+			// Ленивое продолжение абзаца внутри элемента
+			item = append(item, strings.TrimLeft(line, " "))
+			continue
+		}
+		break
+	}
+	items = append(items, item)
+
+	for _, lines := range items {
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		list.children = append(list.children, &mdBlock{kind: mdItem, children: md.parseBlocks(lines)})
+	}
+	return list, i
+}
+
+// markerContent возвращает содержимое строки после маркера списка
+func markerContent(line string, m mdListMarker) string {
+	if m.indent >= len(line) {
+		return ""
+	}
+	return line[m.indent:]
+}
+
+// hasContent сообщает, есть ли в строках элемента что-то кроме пустых строк
+func hasContent(lines []string) bool {
+	for _, line := range lines {
+		if !isBlankLine(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTable разбирает таблицу GFM: строку заголовка, строку выравнивания и строки данных
+func (md *markdown) parseTable(lines []string, i int) (*mdBlock, int, bool) {
+	header := splitTableRow(lines[i])
+	delims := splitTableRow(lines[i+1])
+	if len(header) != len(delims) {
+		return nil, i, false
+	}
+
+	table := &mdBlock{kind: mdTable, header: header}
+	for _, d := range delims {
+		left, right := strings.HasPrefix(d, ":"), strings.HasSuffix(d, ":")
+		switch {
+		case left && right:
+			table.align = append(table.align, "center")
+		case right:
+			table.align = append(table.align, "right")
+		case left:
+			table.align = append(table.align, "left")
+		default:
+			table.align = append(table.align, "")
+		}
+	}
+
+	for i += 2; i < len(lines); i++ {
+		line := lines[i]
+		if isBlankLine(line) || md.startsBlock(line) || !strings.Contains(line, "|") {
+			break
+		}
+		cells := splitTableRow(line)
+		row := make([]string, len(header))
+		copy(row, cells)
+		table.rows = append(table.rows, row)
+	}
+	return table, i, true
+}
+
+// splitTableRow делит строку таблицы на ячейки с учётом \| и кода в обратных кавычках
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// parseFootnoteDef разбирает определение сноски [^label]: текст с продолжением в отступе
+func (md *markdown) parseFootnoteDef(lines []string, i int, label, first string) int {
+	content := []string{first}
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if isBlankLine(line) {
+			content = append(content, "")
+			continue
+		}
+		if leadingSpaces(line) >= 4 {
+			content = append(content, line[4:])
+			continue
+		}
+		if content[len(content)-1] != "" && !md.startsBlock(line) && !mdFootnoteDef.MatchString(strings.TrimLeft(line, " ")) {
+			content = append(content, line)
+			continue
+		}
+		break
+	}
+	key := normalizeLinkLabel(label)
+	if _, exists := md.footnotes[key]; !exists {
+		md.footnotes[key] = md.parseBlocks(content)
+	}
+	return i
+}
+
+// normalizeLinkLabel приводит метку ссылки к каноническому виду для сравнения
+func normalizeLinkLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func trimTitleQuotes(title string) string {
+	if len(title) >= 2 {
+		return title[1 : len(title)-1]
+	}
+	return title
+}
+
+// unescapeMarkdown убирает обратную косую черту перед знаками пунктуации
+func unescapeMarkdown(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+// renderBlocks выводит HTML блоков. В плотном (tight) списке абзацы выводятся без <p>.
+func (md *markdown) renderBlocks(sb *strings.Builder, blocks []*mdBlock, tight bool) {
+	for i, b := range blocks {
+		switch b.kind {
+		case mdParagraph:
+			if tight {
+				sb.WriteString(md.inline(b.text))
+				if i < len(blocks)-1 {
+					sb.WriteByte('\n')
+				}
+			} else {
+				sb.WriteString("<p>" + md.inline(b.text) + "</p>\n")
+			}
+		case mdHeading:
+			md.renderHeading(sb, b)
+		case mdCode:
+			if b.info != "" {
+				sb.WriteString(`<pre><code class="language-` + html.EscapeString(b.info) + `">`)
+			} else {
+				sb.WriteString("<pre><code>")
+			}
+			sb.WriteString(html.EscapeString(b.text) + "</code></pre>\n")
+		case mdRule:
+			sb.WriteString("<hr />\n")
+		case mdQuote:
+			sb.WriteString("<blockquote>\n")
+			md.renderBlocks(sb, b.children, false)
+			sb.WriteString("</blockquote>\n")
+		case mdList:
+			md.renderList(sb, b)
+		case mdHTML:
+			sb.WriteString(b.text + "\n")
+		case mdTable:
+			md.renderTable(sb, b)
+		}
+	}
+}
+
+// renderHeading выводит заголовок с якорем id, построенным по его тексту или заданным через {#id}
+func (md *markdown) renderHeading(sb *strings.Builder, b *mdBlock) {
+	text := strings.TrimSpace(b.text)
+	id := ""
+	if sub := mdHeadingID.FindStringSubmatch(text); sub != nil {
+		id = sub[1]
+		text = text[:len(text)-len(sub[0])]
+	}
+	content := md.inline(text)
+	if id == "" {
+		id = slugify(html.UnescapeString(mdHTMLTagStrip.ReplaceAllString(content, "")))
+		if id == "" {
+			id = "section"
+		}
+	}
+	if n := md.headingIDs[id]; n > 0 {
+		md.headingIDs[id] = n + 1
+		id = fmt.Sprintf("%s-%d", id, n)
+	} else {
+		md.headingIDs[id] = 1
+	}
+	fmt.Fprintf(sb, "<h%d id=\"%s\">%s</h%d>\n", b.level, html.EscapeString(id), content, b.level)
+}
+
+// renderList выводит список и его элементы
+func (md *markdown) renderList(sb *strings.Builder, b *mdBlock) {
+	tag := "ul"
+	if b.ordered {
+		tag = "ol"
+		if b.start != 1 {
+			fmt.Fprintf(sb, "<ol start=\"%d\">\n", b.start)
+		} else {
+			sb.WriteString("<ol>\n")
+		}
+	} else {
+		sb.WriteString("<ul>\n")
+	}
+	for _, item := range b.children {
+		sb.WriteString("<li>")
+		if len(item.children) > 0 && (!b.tight || item.children[0].kind != mdParagraph) {
+			sb.WriteByte('\n')
+		}
+		md.renderBlocks(sb, item.children, b.tight)
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</" + tag + ">\n")
+}
+
+// renderTable выводит таблицу GFM
+func (md *markdown) renderTable(sb *strings.Builder, b *mdBlock) {
+	cell := func(tag, align, text string) {
+		if align != "" {
+			fmt.Fprintf(sb, "<%s align=\"%s\">%s</%s>\n", tag, align, md.inline(text), tag)
+		} else {
+			fmt.Fprintf(sb, "<%s>%s</%s>\n", tag, md.inline(text), tag)
+		}
+	}
+	sb.WriteString("<table>\n<thead>\n<tr>\n")
+	for i, h := range b.header {
+		cell("th", b.align[i], h)
+	}
+	sb.WriteString("</tr>\n</thead>\n")
+	if len(b.rows) > 0 {
+		sb.WriteString("<tbody>\n")
+		for _, row := range b.rows {
+			sb.WriteString("<tr>\n")
+			for i, c := range row {
+				cell("td", b.align[i], c)
+			}
+			sb.WriteString("</tr>\n")
+		}
+		sb.WriteString("</tbody>\n")
+	}
+	sb.WriteString("</table>\n")
+}
+
+// renderFootnotes выводит раздел сносок в порядке первых ссылок на них
+func (md *markdown) renderFootnotes(sb *strings.Builder) {
+	if len(md.footnoteOrder) == 0 {
+		return
+	}
+	sb.WriteString("<section class=\"footnotes\">\n<ol>\n")
+	// Текст сноски может ссылаться на другие сноски, поэтому список растёт во время обхода
+	for i := 0; i < len(md.footnoteOrder); i++ {
+		label := md.footnoteOrder[i]
+		id := html.EscapeString(slugify(label))
+		backref := fmt.Sprintf(" <a href=\"#fnref-%s\" class=\"footnote-backref\">&#8617;</a>", id)
+
+		var body strings.Builder
+		md.renderBlocks(&body, md.footnotes[label], false)
+		content := strings.TrimRight(body.String(), "\n")
+		if strings.HasSuffix(content, "</p>") {
+			content = strings.TrimSuffix(content, "</p>") + backref + "</p>"
+		} else {
+			content += backref
+		}
+		fmt.Fprintf(sb, "<li id=\"fn-%s\">\n%s\n</li>\n", id, content)
+	}
+	sb.WriteString("</ol>\n</section>\n")
+}
+
+// mdToken — элемент строчной разметки: готовый HTML или последовательность разделителей * _ ~
+type mdToken struct {
+	html      string
+	delim     byte
+	count     int
+	origCount int
+	canOpen   bool
+	canClose  bool
+	openTags  string
+	closeTags string
+}
+
+// inline преобразует строчную разметку абзаца в HTML
+func (md *markdown) inline(text string) string {
+	var tokens []*mdToken
+	var textBuf strings.Builder
+	flushText := func() {
+		if textBuf.Len() > 0 {
+			tokens = append(tokens, &mdToken{html: textBuf.String()})
+			textBuf.Reset()
+		}
+	}
+	emit := func(s string) {
+		flushText()
+		tokens = append(tokens, &mdToken{html: s})
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			emit("<br />\n")
+			i += 2
+			for i < len(text) && text[i] == ' ' {
+				i++
+			}
+			continue
+		case c == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]):
+			textBuf.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '\n':
+			// Два пробела в конце строки дают жёсткий перенос
+			content := textBuf.String()
+			trimmed := strings.TrimRight(content, " ")
+			textBuf.Reset()
+			textBuf.WriteString(trimmed)
+			if len(content)-len(trimmed) >= 2 {
+				emit("<br />\n")
+			} else {
+				textBuf.WriteByte('\n')
+			}
+			i++
+			for i < len(text) && text[i] == ' ' {
+				i++
+			}
+			continue
+		case c == '`':
+			if code, next, ok := parseCodeSpan(text, i); ok {
+				emit("<code>" + html.EscapeString(code) + "</code>")
+				i = next
+				continue
+			}
+			run := i
+			for run < len(text) && text[run] == '`' {
+				run++
+			}
+			textBuf.WriteString(text[i:run])
+			i = run
+			continue
+		case c == '<':
+			rest := text[i:]
+			if sub := mdAutolink.FindStringSubmatch(rest); sub != nil {
+				emit(fmt.Sprintf(`<a href="%s">%s</a>`, escapeLinkURL(sub[1]), html.EscapeString(sub[1])))
+				i += len(sub[0])
+				continue
+			}
+			if sub := mdEmailLink.FindStringSubmatch(rest); sub != nil {
+				emit(fmt.Sprintf(`<a href="mailto:%s">%s</a>`, html.EscapeString(sub[1]), html.EscapeString(sub[1])))
+				i += len(sub[0])
+				continue
+			}
+			if tag := mdInlineTag.FindString(rest); tag != "" && md.rawHTML {
+				emit(tag)
+				i += len(tag)
+				continue
+			}
+		case c == '&':
+			if entity := mdEntity.FindString(text[i:]); entity != "" {
+				textBuf.WriteString(entity)
+				i += len(entity)
+				continue
+			}
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			if out, next, ok := md.parseLink(text, i+1, true); ok {
+				emit(out)
+				i = next
+				continue
+			}
+		case c == '[':
+			if sub := mdFootnoteRef.FindStringSubmatch(text[i:]); sub != nil {
+				if out, ok := md.footnoteRef(sub[1]); ok {
+					emit(out)
+					i += len(sub[0])
+					continue
+				}
+			}
+			if out, next, ok := md.parseLink(text, i, false); ok {
+				emit(out)
+				i = next
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			run := i
+			for run < len(text) && text[run] == c {
+				run++
+			}
+			flushText()
+			tokens = append(tokens, newDelimToken(text, i, run))
+			i = run
+			continue
+		}
+		writeEscapedByte(&textBuf, c)
+		i++
+	}
+	flushText()
+
+	processEmphasis(tokens)
+
+	var sb strings.Builder
+	for _, t := range tokens {
+		if t.delim == 0 {
+			sb.WriteString(t.html)
+			continue
+		}
+		sb.WriteString(t.closeTags)
+		sb.WriteString(strings.Repeat(string(t.delim), t.count))
+		sb.WriteString(t.openTags)
+	}
+	return sb.String()
+}
+
+// writeEscapedByte записывает байт текста, экранируя специальные символы HTML.
+// Остальные байты копируются как есть, чтобы не разрывать многобайтовые символы UTF-8.
+func writeEscapedByte(sb *strings.Builder, c byte) {
+	switch c {
+	case '<':
+		sb.WriteString("&lt;")
+	case '>':
+		sb.WriteString("&gt;")
+	case '&':
+		sb.WriteString("&amp;")
+	case '"':
+		sb.WriteString("&quot;")
+	default:
+		sb.WriteByte(c)
+	}
+}
+
+// newDelimToken создаёт токен последовательности разделителей text[start:end]
+// и определяет, может ли она открывать или закрывать выделение
+func newDelimToken(text string, start, end int) *mdToken {
+	c := text[start]
+	prev, next := ' ', ' '
+	if start > 0 {
+		prev, _ = utf8.DecodeLastRuneInString(text[:start])
+	}
+	if end < len(text) {
+		next, _ = utf8.DecodeRuneInString(text[end:])
+	}
+
+	isPunct := func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) }
+	leftFlanking := !unicode.IsSpace(next) && (!isPunct(next) || unicode.IsSpace(prev) || isPunct(prev))
+	rightFlanking := !unicode.IsSpace(prev) && (!isPunct(prev) || unicode.IsSpace(next) || isPunct(next))
+
+	t := &mdToken{delim: c, count: end - start, origCount: end - start}
+	switch c {
+	case '_':
+		t.canOpen = leftFlanking && (!rightFlanking || isPunct(prev))
+		t.canClose = rightFlanking && (!leftFlanking || isPunct(next))
+	case '~':
+		t.canOpen = leftFlanking && t.count == 2
+		t.canClose = rightFlanking && t.count == 2
+	default:
+		t.canOpen = leftFlanking
+		t.canClose = rightFlanking
+	}
+	return t
+}
+
+// processEmphasis сопоставляет открывающие и закрывающие разделители и расставляет теги выделения
+func processEmphasis(tokens []*mdToken) {
+	for ci := 0; ci < len(tokens); ci++ {
+		closer := tokens[ci]
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+		for closer.count > 0 {
+			oi := ci - 1
+			for ; oi >= 0; oi-- {
+				opener := tokens[oi]
+				if opener.delim != closer.delim || !opener.canOpen || opener.count == 0 {
+					continue
+				}
+				// Правило трёх из CommonMark для разделителей, которые могут и открывать, и закрывать
+				if (opener.canClose || closer.canOpen) && (opener.origCount+closer.origCount)%3 == 0 &&
+					!(opener.origCount%3 == 0 && closer.origCount%3 == 0) {
+					continue
+				}
+				break
+			}
+			if oi < 0 {
+				break
+			}
+			opener := tokens[oi]
+
+			n := 1
+			if opener.count >= 2 && closer.count >= 2 {
+				n = 2
+			}
+			tag := "em"
+			switch {
+			case closer.delim == '~':
+				tag = "del"
+			case n == 2:
+				tag = "strong"
+			}
+			opener.count -= n
+			closer.count -= n
+			opener.openTags = "<" + tag + ">" + opener.openTags
+			closer.closeTags += "</" + tag + ">"
+
+			// Разделители между парой остаются обычным текстом
+			for k := oi + 1; k < ci; k++ {
+				if tokens[k].delim != 0 {
+					tokens[k].canOpen = false
+					tokens[k].canClose = false
+				}
+			}
+		}
+	}
+}
+
+// parseCodeSpan разбирает фрагмент кода в обратных кавычках, начинающийся в позиции i
+func parseCodeSpan(text string, i int) (string, int, bool) {
+	run := i
+	for run < len(text) && text[run] == '`' {
+		run++
+	}
+	n := run - i
+	for j := run; j < len(text); {
+		if text[j] != '`' {
+			j++
+			continue
+		}
+		k := j
+		for k < len(text) && text[k] == '`' {
+			k++
+		}
+		if k-j == n {
+			code := strings.ReplaceAll(text[run:j], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			return code, k, true
+		}
+		j = k
+	}
+	return "", i, false
+}
+
+// footnoteRef выводит ссылку на сноску и присваивает ей номер при первом упоминании
+func (md *markdown) footnoteRef(label string) (string, bool) {
+	key := normalizeLinkLabel(label)
+	if _, exists := md.footnotes[key]; !exists {
+		return "", false
+	}
+	n, seen := md.footnoteIndex[key]
+	if !seen {
+		md.footnoteOrder = append(md.footnoteOrder, key)
+		n = len(md.footnoteOrder)
+		md.footnoteIndex[key] = n
+	}
+	id := html.EscapeString(slugify(key))
+	return fmt.Sprintf(`<sup class="footnote-ref" id="fnref-%s"><a href="#fn-%s">%d</a></sup>`, id, id, n), true
+}
+
+// parseLink разбирает ссылку или изображение, начинающиеся с '[' в позиции i
+func (md *markdown) parseLink(text string, i int, image bool) (string, int, bool) {
+	end := findClosingBracket(text, i)
+	if end < 0 {
+		return "", i, false
+	}
+	label := text[i+1 : end]
+	next := end + 1
+
+	var link mdLink
+	found := false
+	if next < len(text) && text[next] == '(' {
+		if dest, title, after, ok := parseLinkDestination(text, next); ok {
+			link = mdLink{url: dest, title: title}
+			next = after
+			found = true
+		}
+	}
+	if !found {
+		ref := label
+		if next < len(text) && text[next] == '[' {
+			if refEnd := strings.IndexByte(text[next:], ']'); refEnd >= 0 {
+				if r := text[next+1 : next+refEnd]; r != "" {
+					ref = r
+				}
+				next += refEnd + 1
+			}
+		}
+		link, found = md.refs[normalizeLinkLabel(ref)]
+		if !found {
+			return "", i, false
+		}
+	}
+
+	title := ""
+	if link.title != "" {
+		title = ` title="` + html.EscapeString(link.title) + `"`
+	}
+	if image {
+		alt := html.UnescapeString(mdHTMLTagStrip.ReplaceAllString(md.inline(label), ""))
+		return fmt.Sprintf(`<img src="%s" alt="%s"%s />`, escapeLinkURL(link.url), html.EscapeString(alt), title), next, true
+	}
+	return fmt.Sprintf(`<a href="%s"%s>%s</a>`, escapeLinkURL(link.url), title, md.inline(label)), next, true
+}
+
+// findClosingBracket ищет ']' парную '[' в позиции i с учётом вложенности, экранирования и кода
+func findClosingBracket(text string, i int) int {
+	depth := 0
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '`':
+			if _, next, ok := parseCodeSpan(text, j); ok {
+				j = next - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// parseLinkDestination разбирает (адрес "заголовок") после текста ссылки
+func parseLinkDestination(text string, i int) (string, string, int, bool) {
+	j := i + 1
+	skip := func() {
+		for j < len(text) && (text[j] == ' ' || text[j] == '\t' || text[j] == '\n') {
+			j++
+		}
+	}
+	skip()
+
+	var dest string
+	if j < len(text) && text[j] == '<' {
+		end := strings.IndexAny(text[j+1:], ">\n")
+		if end < 0 || text[j+1+end] != '>' {
+			return "", "", i, false
+		}
+		dest = text[j+1 : j+1+end]
+		j += end + 2
+	} else {
+		start, depth := j, 0
+		for ; j < len(text); j++ {
+			c := text[j]
+			if c == '\\' && j+1 < len(text) {
+				j++
+				continue
+			}
+			if c == ' ' || c == '\t' || c == '\n' {
+				break
+			}
+			if c == '(' {
+				depth++
+			}
+			if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		dest = text[start:j]
+	}
+
+	title := ""
+	before := j
+	skip()
+	if j < len(text) && j > before && strings.IndexByte(`"'(`, text[j]) >= 0 {
+		closing := text[j]
+		if closing == '(' {
+			closing = ')'
+		}
+		end := strings.IndexByte(text[j+1:], closing)
+		if end < 0 {
+			return "", "", i, false
+		}
+		title = text[j+1 : j+1+end]
+		j += end + 2
+		skip()
+	}
+	if j >= len(text) || text[j] != ')' {
+		return "", "", i, false
+	}
+	return safeLinkURL(unescapeMarkdown(dest)), unescapeMarkdown(title), j + 1, true
+}
+
+// safeLinkURL пропускает относительные адреса и схемы, разрешённые для href в шаблонах,
+// а остальные, например javascript: и data:, заменяет на #unsafe
+func safeLinkURL(url string) string {
+	if !safeURLScheme(url) {
+		return "#" + escUnsafe
+	}
+	return url
+}
+
+// escapeLinkURL проверяет схему адреса ссылки, кодирует пробелы и экранирует адрес для атрибута HTML
+func escapeLinkURL(url string) string {
+	return html.EscapeString(strings.ReplaceAll(safeLinkURL(url), " ", "%20"))
+}
+
+// slugify строит идентификатор для якоря или адреса: буквы и цифры в нижнем регистре через дефис
+func slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			dash = false
+			sb.WriteRune(r)
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			dash = true
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты преобразования Markdown в HTML.
+*/
+
+package main
+
+import "testing"
+
+// mdTest — исходный Markdown и ожидаемый HTML
+type mdTest struct {
+	name string
+	src  string
+	want string
+}
+
+func runMarkdownTests(t *testing.T, tests []mdTest) {
+	t.Helper()
+	runMarkdownTestsHTML(t, tests, false)
+}
+
+// runMarkdownTestsHTML проверяет преобразование с заданным build.markdownHTML
+func runMarkdownTestsHTML(t *testing.T, tests []mdTest, rawHTML bool) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := renderMarkdown(tt.src, rawHTML); got != tt.want {
+			t.Errorf("%s:\nполучено  %q\nожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownEmphasis(t *testing.T) {
+	runMarkdownTests(t, []mdTest{
+		{"звёздочки", "*a* **b** ***c***", "<p><em>a</em> <strong>b</strong> <em><strong>c</strong></em></p>"},
+		{"подчёркивания", "_d_ __e__", "<p><em>d</em> <strong>e</strong></p>"},
+		{"внутри слова", "a*b*c snake_case_word", "<p>a<em>b</em>c snake_case_word</p>"},
+		{"вложенное", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>"},
+		{"непарные", "**a*", "<p>*<em>a</em></p>"},
+		{"экранированные", `\*not\*`, "<p>*not*</p>"},
+		{"пробел после разделителя", "a * b *", "<p>a * b *</p>"},
+	})
+}
+
+func TestMarkdownLists(t *testing.T) {
+	runMarkdownTests(t, []mdTest{
+		{"маркированный", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>"},
+		{"нумерованный", "1. x\n2. y", "<ol>\n<li>x</li>\n<li>y</li>\n</ol>"},
+		{"с другого номера", "3) z", "<ol start=\"3\">\n<li>z</li>\n</ol>"},
+		{"свободный", "- a\n\n- b", "<ul>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ul>"},
+		{"вложенный", "- a\n  - b\n- c", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n<li>c</li>\n</ul>"},
+		{"смена маркера", "- a\n* b", "<ul>\n<li>a</li>\n</ul>\n<ul>\n<li>b</li>\n</ul>"},
+	})
+}
+
+func TestMarkdownCode(t *testing.T) {
+	runMarkdownTests(t, []mdTest{
+		{"код в строке", "`code`", "<p><code>code</code></p>"},
+		{"обратная кавычка внутри", "`` a ` b ``", "<p><code>a ` b</code></p>"},
+		{"экранирование в коде", "`a<b>` `*x*`", "<p><code>a&lt;b&gt;</code> <code>*x*</code></p>"},
+		{"незакрытый", "`a", "<p>`a</p>"},
+		{"блок с языком", "```go\nfunc() {}\n<tag>\n```", "<pre><code class=\"language-go\">func() {}\n&lt;tag&gt;\n</code></pre>"},
+		{"тильды", "~~~\nx\n~~~", "<pre><code>x\n</code></pre>"},
+		{"незакрытый блок", "```\nx", "<pre><code>x\n</code></pre>"},
+		{"отступ", "    indented\n      more", "<pre><code>indented\n  more\n</code></pre>"},
+	})
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	runMarkdownTests(t, []mdTest{
+		{"с заголовком", `[a](/url "title")`, "<p><a href=\"/url\" title=\"title\">a</a></p>"},
+		{"в угловых скобках", "[b](<my url>)", "<p><a href=\"my%20url\">b</a></p>"},
+		{"пробел без скобок", "[c](http://x.com/a b)", "<p>[c](http://x.com/a b)</p>"},
+		{"скобки в адресе", "[d](/a(b)c)", "<p><a href=\"/a(b)c\">d</a></p>"},
+		{"ссылка по метке", "[d][Ref]\n\n[ref]: /r 'T'", "<p><a href=\"/r\" title=\"T\">d</a></p>"},
+		{"изображение", `![img](/i.png "t")`, "<p><img src=\"/i.png\" alt=\"img\" title=\"t\" /></p>"},
+		{"автоссылка", "<https://x.com>", "<p><a href=\"https://x.com\">https://x.com</a></p>"},
+		{"кавычки в адресе", `[q](/a"b)`, "<p><a href=\"/a&#34;b\">q</a></p>"},
+	})
+}
+
+func TestMarkdownHTML(t *testing.T) {
+	runMarkdownTestsHTML(t, []mdTest{
+		{"блок HTML", "<div>\n*raw*\n</div>", "<div>\n*raw*\n</div>"},
+		{"HTML в строке", "text <span>inline</span> <!-- c -->", "<p>text <span>inline</span> <!-- c --></p>"},
+		{"блок прерывает абзац", "text\n<div>x</div>", "<p>text</p>\n<div>x</div>"},
+	}, true)
+	runMarkdownTests(t, []mdTest{
+		{"блок HTML экранируется", "<div>\n*raw*\n</div>", "<p>&lt;div&gt;\n<em>raw</em>\n&lt;/div&gt;</p>"},
+		{"скрипт экранируется", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"HTML в строке экранируется", "a <img src=x onerror=alert(1)> <!-- c -->", "<p>a &lt;img src=x onerror=alert(1)&gt; &lt;!-- c --&gt;</p>"},
+	})
+}
+
+func TestMarkdownUnsafeURLs(t *testing.T) {
+	runMarkdownTests(t, []mdTest{
+		{"javascript в ссылке", "[x](javascript:alert(1))", "<p><a href=\"#unsafe\">x</a></p>"},
+		{"регистр и пробелы", "[x](<JavaScript :alert(1)>)", "<p><a href=\"#unsafe\">x</a></p>"},
+		{"data в изображении", "![i](data:text/html;base64,PHNjcmlwdD4=)", "<p><img src=\"#unsafe\" alt=\"i\" /></p>"},
+		{"ссылка по метке", "[x][r]\n\n[r]: vbscript:msgbox", "<p><a href=\"#unsafe\">x</a></p>"},
+		{"автоссылка", "<javascript:alert(1)>", "<p><a href=\"#unsafe\">javascript:alert(1)</a></p>"},
+		{"разрешённые схемы", "[a](https://x.com) [b](mailto:a@b.c) [c](/p?q=a:b) [d](#top)",
+			"<p><a href=\"https://x.com\">a</a> <a href=\"mailto:a@b.c\">b</a> <a href=\"/p?q=a:b\">c</a> <a href=\"#top\">d</a></p>"},
+	})
+}
+
+func TestMarkdownEscaping(t *testing.T) {
+	runMarkdownTests(t, []mdTest{
+		{"символы HTML", `< > " ' & x`, "<p>&lt; &gt; &quot; ' &amp; x</p>"},
+		{"сущности", "&amp; &copy;", "<p>&amp; &copy;</p>"},
+		{"обратная косая черта", `\# \[x\] \\`, "<p># [x] \\</p>"},
+		{"разрыв строки", "line  \nbreak\\\nnext", "<p>line<br />\nbreak<br />\nnext</p>"},
+	})
+}
+
+func TestMarkdownBlocks(t *testing.T) {
+	runMarkdownTests(t, []mdTest{
+		{"заголовки", "# H1\n## H *2* ##", "<h1 id=\"h1\">H1</h1>\n<h2 id=\"h-2\">H <em>2</em></h2>"},
+		{"setext", "Setext\n===", "<h1 id=\"setext\">Setext</h1>"},
+		{"цитата", "> quote\n> more", "<blockquote>\n<p>quote\nmore</p>\n</blockquote>"},
+		{"линия", "---", "<hr />"},
+		{"таблица", "| a | b |\n|:--|--:|\n| 1 | 2 |",
+			"<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n" +
+				"<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\">2</td>\n</tr>\n</tbody>\n</table>"},
+		{"сноска", "foot[^1]\n\n[^1]: note",
+			"<p>foot<sup class=\"footnote-ref\" id=\"fnref-1\"><a href=\"#fn-1\">1</a></sup></p>\n" +
+				"<section class=\"footnotes\">\n<ol>\n<li id=\"fn-1\">\n<p>note <a href=\"#fnref-1\" class=\"footnote-backref\">&#8617;</a></p>\n</li>\n</ol>\n</section>"},
+	})
+}
@@ -0,0 +1,504 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Минификация HTML, CSS и JavaScript результата сборки.
+*/
+
+package main
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// minifies сообщает, нужно ли минифицировать результат шаблона template
+func (opts *Options) minifies(template string) bool {
+	if !opts.Config.Minify.Enabled {
+		return false
+	}
+	for _, excluded := range opts.Config.Minify.Exclude {
+		if excluded == template {
+			return false
+		}
+	}
+	return true
+}
+
+// minifiesAsset сообщает, нужно ли минифицировать копируемый файл: CSS и JavaScript,
+// кроме уже минифицированных *.min.css и *.min.js
+func (opts *Options) minifiesAsset(name string) bool {
+	if !opts.Config.Minify.Enabled {
+		return false
+	}
+	ext := path.Ext(name)
+	return (ext == ".css" || ext == ".js") && !strings.HasSuffix(name, ".min"+ext)
+}
+
+// minifyAsset минифицирует содержимое CSS- или JavaScript-файла name
+func minifyAsset(name string, content []byte) []byte {
+	switch path.Ext(name) {
+	case ".css":
+		return []byte(minifyCSS(string(content)))
+	case ".js":
+		return []byte(minifyJS(string(content)))
+	}
+	return content
+}
+
+// htmlRawElements — элементы, содержимое которых не разбирается как HTML
+var htmlRawElements = map[string]bool{"pre": true, "textarea": true, "script": true, "style": true}
+
+// htmlSilentElements — элементы, пробелы между которыми браузер не выводит. Пробелы между двумя
+// такими тегами удаляются, между остальными сжимаются до одного, как их и показывает браузер.
+var htmlSilentElements = map[string]bool{
+	"!doctype": true, "html": true, "head": true, "body": true, "title": true, "meta": true, "link": true,
+	"base": true, "table": true, "caption": true, "colgroup": true, "col": true, "thead": true,
+	"tbody": true, "tfoot": true, "tr": true, "td": true, "th": true, "select": true, "option": true,
+	"optgroup": true,
+}
+
+// minifyHTML сжимает пробелы в тексте HTML и удаляет комментарии, кроме условных (<!--[if ...]>).
+// Содержимое <pre> и <textarea> и атрибуты тегов не меняются, <style> и <script> минифицируются
+// как CSS и JavaScript (скрипты с нестандартным type, например application/ld+json, остаются как есть).
+func minifyHTML(input []byte) []byte {
+	src := string(input)
+	var out bytes.Buffer
+	out.Grow(len(src))
+	var pending []byte // Текст между тегами, который ещё не записан
+	prevTag := ""      // Имя предыдущего тега, если между ним и текущим местом нет записанного текста
+	inHead := false
+	// Пробелы между тегами, которые браузер не выводит, не нужны вовсе; внутри <head>
+	// к таким тегам относятся и <script> и <style>
+	silent := func(tag string) bool {
+		return htmlSilentElements[tag] || inHead && (tag == "script" || tag == "style")
+	}
+	flush := func(nextTag string) {
+		if len(pending) == 0 {
+			return
+		}
+		text := collapseSpaces(pending)
+		pending = pending[:0]
+		if text == " " && prevTag != "" && nextTag != "" && silent(prevTag) && silent(nextTag) {
+			return
+		}
+		out.WriteString(text)
+		prevTag = ""
+	}
+
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			pending = append(pending, src[i])
+			i++
+			continue
+		}
+		if strings.HasPrefix(src[i:], "<!--") {
+			length := len(src) - i
+			if end := strings.Index(src[i+4:], "-->"); end >= 0 {
+				length = end + 7
+			}
+			if strings.HasPrefix(src[i:], "<!--[if") || strings.HasPrefix(src[i:], "<!--<![endif]") {
+				flush("")
+				out.WriteString(src[i : i+length])
+				prevTag = ""
+			}
+			i += length
+			continue
+		}
+		name, closing, length := htmlTag(src[i:])
+		if length == 0 {
+			pending = append(pending, '<')
+			i++
+			continue
+		}
+		tag := src[i : i+length]
+		flush(name)
+		out.WriteString(tag)
+		i += length
+		prevTag = name
+		if name == "head" {
+			inHead = !closing
+		}
+		if closing || !htmlRawElements[name] || strings.HasSuffix(tag, "/>") {
+			continue
+		}
+
+		// Содержимое <pre>, <textarea>, <script> и <style> идёт до закрывающего тега
+		end := indexFold(src[i:], "</"+name)
+		if end < 0 {
+			end = len(src) - i
+		}
+		content := src[i : i+end]
+		switch {
+		case name == "style":
+			out.WriteString(minifyCSS(content))
+		case name == "script" && isJavaScriptType(htmlAttr(tag, "type")):
+			out.WriteString(minifyJS(content))
+		default:
+			out.WriteString(content)
+		}
+		i += end
+	}
+	flush("")
+	return out.Bytes()
+}
+
+// htmlTag распознаёт тег в начале s и возвращает его имя в нижнем регистре, признак закрывающего тега
+// и длину тега. Длина 0 означает, что "<" не начинает тег. Кавычки в значениях атрибутов учитываются.
+func htmlTag(s string) (string, bool, int) {
+	i := 1
+	closing := strings.HasPrefix(s[i:], "/")
+	if closing {
+		i++
+	}
+	doctype := !closing && strings.HasPrefix(s[i:], "!")
+	if doctype {
+		i++
+	}
+	n := tagNameLen(s[i:])
+	if n == 0 {
+		return "", false, 0
+	}
+	name := strings.ToLower(s[i : i+n])
+	if doctype {
+		name = "!" + name
+	}
+	var quote byte
+	for i += n; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return name, closing, i + 1
+		}
+	}
+	return "", false, 0
+}
+
+// htmlAttr возвращает значение атрибута name тега или пустую строку
+func htmlAttr(tag, name string) string {
+	lower := strings.ToLower(tag)
+	for from := 0; ; {
+		i := strings.Index(lower[from:], name)
+		if i < 0 {
+			return ""
+		}
+		i += from
+		from = i + len(name)
+		if i == 0 || !isHTMLSpace(lower[i-1]) {
+			continue
+		}
+		rest := strings.TrimLeft(lower[from:], " \t\n\r\f")
+		if !strings.HasPrefix(rest, "=") {
+			return ""
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\n\r\f")
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			if end := strings.IndexByte(rest[1:], rest[0]); end >= 0 {
+				return rest[1 : end+1]
+			}
+			return ""
+		}
+		end := strings.IndexAny(rest, " \t\n\r\f>")
+		if end < 0 {
+			end = len(rest)
+		}
+		return strings.TrimSuffix(rest[:end], "/")
+	}
+}
+
+// isJavaScriptType сообщает, что <script> с атрибутом type содержит JavaScript
+func isJavaScriptType(typ string) bool {
+	typ = strings.TrimSpace(typ)
+	return typ == "" || typ == "module" || strings.Contains(typ, "javascript") || strings.Contains(typ, "ecmascript")
+}
+
+// indexFold ищет substr в s без учёта регистра
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if hasPrefixFold(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// collapseSpaces заменяет каждую последовательность пробельных символов HTML одним пробелом
+func collapseSpaces(text []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	space := false
+	for _, c := range text {
+		if isHTMLSpace(c) {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteByte(c)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// minifyCSS удаляет комментарии и лишние пробелы. Строки копируются как есть; пробелы вокруг
+// "+" и "-" сохраняются, потому что внутри calc() они значимы.
+func minifyCSS(src string) string {
+	var sb strings.Builder
+	sb.Grow(len(src))
+	space := false
+	var last byte
+	tightAfter := func(c byte) bool { return strings.IndexByte("{};,>~:(", c) >= 0 }
+	tightBefore := func(c byte) bool { return strings.IndexByte("{};,>~)", c) >= 0 }
+	write := func(c byte) {
+		if space && last != 0 && !tightAfter(last) && !tightBefore(c) {
+			sb.WriteByte(' ')
+		}
+		space = false
+		sb.WriteByte(c)
+		last = c
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return sb.String()
+			}
+			i += end + 3
+		case isHTMLSpace(c):
+			space = true
+		case c == '"' || c == '\'':
+			write(c)
+			for i++; i < len(src); i++ {
+				sb.WriteByte(src[i])
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					sb.WriteByte(src[i])
+					continue
+				}
+				if src[i] == c {
+					break
+				}
+			}
+			last = c
+		case c == '}' && last == ';':
+			// Последняя точка с запятой в блоке не нужна
+			s := sb.String()
+			sb.Reset()
+			sb.WriteString(s[:len(s)-1])
+			space = false
+			sb.WriteByte(c)
+			last = c
+		default:
+			write(c)
+		}
+	}
+	return sb.String()
+}
+
+// jsKeywordsBeforeRegexp — слова, после которых "/" начинает регулярное выражение, а не деление
+var jsKeywordsBeforeRegexp = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "instanceof": true, "yield": true, "await": true,
+}
+
+// jsMinifier удаляет из JavaScript комментарии и лишние пробелы, не меняя лексемы.
+// Переводы строк сохраняются там, где от них может зависеть автоматическая вставка точки с запятой.
+type jsMinifier struct {
+	src     string
+	i       int
+	out     strings.Builder
+	last    byte   // Последний записанный непробельный символ
+	word    string // Последнее записанное слово, если last — его часть
+	space   bool
+	newline bool
+}
+
+// minifyJS минифицирует JavaScript консервативно: строки, шаблонные строки и регулярные выражения
+// копируются как есть, пробелы удаляются только рядом со знаками, где они не разделяют лексемы.
+func minifyJS(src string) string {
+	m := &jsMinifier{src: src}
+	m.out.Grow(len(src))
+	m.code(false)
+	return m.out.String()
+}
+
+func isJSWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
+
+// emit записывает символ c, а перед ним — перевод строки или пробел, если они нужны
+func (m *jsMinifier) emit(c byte) {
+	const tight = "{}()[];,:=<>?&|"
+	switch {
+	case m.last == 0:
+	case m.newline && strings.IndexByte("{;,([", m.last) < 0 && strings.IndexByte("}])", c) < 0:
+		m.out.WriteByte('\n')
+	case (m.space || m.newline) && strings.IndexByte(tight, m.last) < 0 && strings.IndexByte(tight, c) < 0:
+		m.out.WriteByte(' ')
+	}
+	m.space, m.newline = false, false
+	m.out.WriteByte(c)
+	if isJSWordChar(c) {
+		if isJSWordChar(m.last) {
+			m.word += string(c)
+		} else {
+			m.word = string(c)
+		}
+	} else {
+		m.word = ""
+	}
+	m.last = c
+}
+
+// regexpAllowed сообщает, что "/" в текущем месте начинает регулярное выражение
+func (m *jsMinifier) regexpAllowed() bool {
+	if m.last == 0 || strings.IndexByte("(,=:[!&|?{};~+-*%<>^", m.last) >= 0 {
+		return true
+	}
+	return m.word != "" && jsKeywordsBeforeRegexp[m.word]
+}
+
+// copyUntil копирует символы как есть до закрывающего quote с учётом экранирования "\"
+func (m *jsMinifier) copyUntil(quote byte) {
+	for ; m.i < len(m.src); m.i++ {
+		c := m.src[m.i]
+		m.out.WriteByte(c)
+		if c == '\\' && m.i+1 < len(m.src) {
+			m.i++
+			m.out.WriteByte(m.src[m.i])
+			continue
+		}
+		if c == quote {
+			m.i++
+			return
+		}
+	}
+}
+
+// code обрабатывает код до конца или, при inTemplate, до "}", закрывающего ${...} шаблонной строки
+func (m *jsMinifier) code(inTemplate bool) {
+	depth := 0
+	for m.i < len(m.src) {
+		c := m.src[m.i]
+		switch {
+		case c == '\n' || c == '\r':
+			m.newline = true
+			m.i++
+		case isHTMLSpace(c) || c == '\v':
+			m.space = true
+			m.i++
+		case c == '/' && m.i+1 < len(m.src) && m.src[m.i+1] == '/':
+			end := strings.IndexAny(m.src[m.i:], "\n\r")
+			if end < 0 {
+				m.i = len(m.src)
+			} else {
+				m.i += end
+			}
+		case c == '/' && m.i+1 < len(m.src) && m.src[m.i+1] == '*':
+			end := strings.Index(m.src[m.i+2:], "*/")
+			if end < 0 {
+				m.i = len(m.src)
+				continue
+			}
+			if strings.ContainsAny(m.src[m.i:m.i+end+2], "\n\r") {
+				m.newline = true
+			} else {
+				m.space = true
+			}
+			m.i += end + 4
+		case c == '"' || c == '\'':
+			m.emit(c)
+			m.i++
+			m.copyUntil(c)
+			m.last, m.word = c, ""
+		case c == '`':
+			m.emit(c)
+			m.i++
+			m.template()
+		case c == '/' && m.regexpAllowed():
+			m.emit(c)
+			m.i++
+			m.regexp()
+		case c == '{':
+			depth++
+			m.emit(c)
+			m.i++
+		case c == '}':
+			if depth == 0 && inTemplate {
+				return
+			}
+			depth--
+			m.emit(c)
+			m.i++
+		default:
+			m.emit(c)
+			m.i++
+		}
+	}
+}
+
+// template копирует шаблонную строку после открывающей "`", минифицируя код внутри ${...}
+func (m *jsMinifier) template() {
+	for m.i < len(m.src) {
+		c := m.src[m.i]
+		switch {
+		case c == '\\' && m.i+1 < len(m.src):
+			m.out.WriteString(m.src[m.i : m.i+2])
+			m.i += 2
+		case c == '`':
+			m.out.WriteByte(c)
+			m.i++
+			m.last, m.word = c, ""
+			return
+		case c == '$' && m.i+1 < len(m.src) && m.src[m.i+1] == '{':
+			m.out.WriteString("${")
+			m.i += 2
+			m.last, m.word, m.space, m.newline = '{', "", false, false
+			m.code(true)
+			m.out.WriteByte('}')
+			m.i++
+		default:
+			m.out.WriteByte(c)
+			m.i++
+		}
+	}
+}
+
+// regexp копирует регулярное выражение после открывающей "/" вместе с классами символов [...]
+func (m *jsMinifier) regexp() {
+	inClass := false
+	for m.i < len(m.src) {
+		c := m.src[m.i]
+		m.out.WriteByte(c)
+		m.i++
+		switch {
+		case c == '\\' && m.i < len(m.src):
+			m.out.WriteByte(m.src[m.i])
+			m.i++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			m.last, m.word = c, ""
+			return
+		case c == '\n':
+			// Незакрытое выражение: дальше снова код
+			m.last, m.word = c, ""
+			return
+		}
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты минификации HTML, CSS и JavaScript.
+*/
+
+package main
+
+import "testing"
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"пробелы в тексте", "<p>a  \n\t b</p>\n\n<p> c </p>", "<p>a b</p> <p> c </p>"},
+		{"пробелы между тегами head", "<!DOCTYPE html>\n<html>\n<head>\n  <title>T</title>\n  <meta charset=\"utf-8\">\n</head>",
+			"<!DOCTYPE html><html><head><title>T</title><meta charset=\"utf-8\"></head>"},
+		{"пробел между строчными тегами", "<b>a</b> <i>b</i>", "<b>a</b> <i>b</i>"},
+		{"таблица", "<table>\n  <tr>\n    <td>1</td>\n  </tr>\n</table>", "<table><tr><td>1</td></tr></table>"},
+		{"скрипт и стиль в head", "<head>\n<style>p{}</style>\n<script>x()</script>\n</head>", "<head><style>p{}</style><script>x()</script></head>"},
+		{"pre и textarea", "<pre>  a\n   b  </pre>\n<textarea>\n x  y\n</textarea>", "<pre>  a\n   b  </pre> <textarea>\n x  y\n</textarea>"},
+		{"регистр закрывающего тега", "<PRE> a  b </PRE>  <p> x </p>", "<PRE> a  b </PRE> <p> x </p>"},
+		{"комментарии", "<p>a<!-- c --> b</p><!---->", "<p>a b</p>"},
+		{"условные комментарии", "<!--[if IE]><p>ie</p><![endif]-->\n<!--<![endif]-->", "<!--[if IE]><p>ie</p><![endif]--> <!--<![endif]-->"},
+		{"атрибуты не меняются", "<a title=\"a  b\"   href='/x'>t</a>", "<a title=\"a  b\"   href='/x'>t</a>"},
+		{"одиночный <", "<p>a < b</p>", "<p>a < b</p>"},
+	}
+	for _, tt := range tests {
+		if got := string(minifyHTML([]byte(tt.src))); got != tt.want {
+			t.Errorf("%s:\nполучено  %q\nожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMinifyHTMLScripts(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"стиль", "<style>\n  p  {  color : red ;  }\n</style>", "<style>p{color :red}</style>"},
+		{"скрипт", "<script>\n  var a = 1 ;\n  // комментарий\n  f( a );\n</script>", "<script>var a=1;f(a);</script>"},
+		{"модуль", "<script type=\"module\">\n  import x from '/x.js'\n</script>", "<script type=\"module\">import x from '/x.js'</script>"},
+		{"ld+json не меняется", "<script type=\"application/ld+json\">\n  { \"a\" : 1 }\n</script>",
+			"<script type=\"application/ld+json\">\n  { \"a\" : 1 }\n</script>"},
+		{"шаблон не меняется", "<script type=\"text/template\"> <p>  x </p> </script>", "<script type=\"text/template\"> <p>  x </p> </script>"},
+	}
+	for _, tt := range tests {
+		if got := string(minifyHTML([]byte(tt.src))); got != tt.want {
+			t.Errorf("%s:\nполучено  %q\nожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"пробелы и комментарии", "/* шапка */\nbody {\n  margin : 0 ;\n  color: red;\n}\n", "body{margin :0;color:red}"},
+		{"селекторы", "a > b ,  c  d { x: y }", "a>b,c d{x:y}"},
+		{"строки", "a::before { content: \"  /* x */  \"; }", "a::before{content:\"  /* x */  \"}"},
+		{"значения через пробел", "p { margin: 0  auto; font: 12px / 1.5  serif }", "p{margin:0 auto;font:12px / 1.5 serif}"},
+		{"calc", "p { width: calc(100% - 2px) }", "p{width:calc(100% - 2px)}"},
+		{"псевдокласс после пробела", "a :hover { x: y }", "a :hover{x:y}"},
+		{"медиазапрос", "@media (max-width: 600px) and (min-width: 100px) { p { x: y } }",
+			"@media (max-width:600px) and (min-width:100px){p{x:y}}"},
+	}
+	for _, tt := range tests {
+		if got := minifyCSS(tt.src); got != tt.want {
+			t.Errorf("%s:\nполучено  %q\nожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Пробелы рядом с +, -, / и кавычками сохраняются: минификатор не разбирает выражения
+// и не может отличить, где они разделяют лексемы
+func TestMinifyJS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"пробелы и комментарии", "/* c */ var a = b + c ; // x\n", "var a=b + c;"},
+		{"пробел между словами", "return  typeof  x", "return typeof x"},
+		{"плюсы не сливаются", "a + +b; c - -d; e++ + f", "a + +b;c - -d;e++ + f"},
+		{"строки", "s = ' a  // b ' + \"c /* d */\"", "s=' a  // b ' + \"c /* d */\""},
+		{"экранирование в строке", `s = "a\" // b"`, `s="a\" // b"`},
+		{"регулярное выражение", "x = a.replace( /\\/  +[/]/g , '' )", "x=a.replace(/\\/  +[/]/g,'')"},
+		{"регулярное выражение после return", "return /a  b/.test(s)", "return /a  b/.test(s)"},
+		{"деление", "x = a / b / c", "x=a / b / c"},
+		{"шаблонная строка", "s = `a  ${ b  + `c  ${ d }` }  e`", "s=`a  ${b + `c  ${d}`}  e`"},
+		{"перевод строки сохраняется", "a = 1\nb = 2\n\n\nreturn\nx", "a=1\nb=2\nreturn\nx"},
+		{"перевод строки после скобки", "f()\n[1].map(g)", "f()\n[1].map(g)"},
+	}
+	for _, tt := range tests {
+		if got := minifyJS(tt.src); got != tt.want {
+			t.Errorf("%s:\nполучено  %q\nожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMinifiesAsset(t *testing.T) {
+	opts := &Options{Config: defaultConfig()}
+	opts.Config.Minify.Enabled = true
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"css/site.css", true},
+		{"js/app.js", true},
+		{"js/vendor.min.js", false},
+		{"css/lib.min.css", false},
+		{"img/logo.svg", false},
+		{"data.json", false},
+	}
+	for _, tt := range tests {
+		if got := opts.minifiesAsset(tt.name); got != tt.want {
+			t.Errorf("minifiesAsset(%q) = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+	opts.Config.Minify.Enabled = false
+	if opts.minifiesAsset("css/site.css") {
+		t.Error("при minify.enabled = false файлы не минифицируются")
+	}
+}
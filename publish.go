@@ -0,0 +1,72 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Даты публикации, черновики и отложенная публикация страниц.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Сообщения об ошибках в атрибутах публикации
+const (
+	msgErrorPageDate  = "Недопустимая дата %s = %q страницы %s: ожидается 2006-01-02, 2006-01-02 15:04, 02.01.2006 или RFC 3339"
+	msgErrorPageDraft = "Недопустимое значение draft = %q страницы %s: ожидается true или false"
+)
+
+// pageDateAttrs — атрибуты страницы, которые распознаются как даты
+var pageDateAttrs = []string{"date", "publishDate", "expiryDate", "lastmod"}
+
+// parsePublishing разбирает даты и признак черновика страницы один раз при её чтении.
+// Дальше сортировка, JSON коллекций и отбор страниц используют model.Dates и model.Draft.
+func parsePublishing(model *Model) error {
+	for _, attr := range pageDateAttrs {
+		value := strings.TrimSpace(model.Data[attr])
+		if value == "" {
+			continue
+		}
+		t, ok := parseDate(value)
+		if !ok {
+			return fmt.Errorf(msgErrorPageDate, attr, value, model.ID)
+		}
+		model.Dates[attr] = t
+	}
+	if value := strings.TrimSpace(model.Data["draft"]); value != "" {
+		draft, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf(msgErrorPageDraft, value, model.ID)
+		}
+		model.Draft = draft
+	}
+	return nil
+}
+
+// publishDate возвращает момент публикации страницы: publishDate, иначе date
+func (m *Model) publishDate() (time.Time, bool) {
+	if t, ok := m.Dates["publishDate"]; ok {
+		return t, true
+	}
+	t, ok := m.Dates["date"]
+	return t, ok
+}
+
+// published сообщает, попадает ли страница в сборку на момент now. Черновики и страницы с датой
+// публикации в будущем собираются только с -drafts и -future, устаревшие (expiryDate) — никогда.
+func (opts *Options) published(model *Model, now time.Time) bool {
+	if model.Draft && !opts.Drafts {
+		return false
+	}
+	if t, ok := model.publishDate(); ok && t.After(now) && !opts.Future {
+		return false
+	}
+	if t, ok := model.Dates["expiryDate"]; ok && !t.After(now) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты дат публикации, черновиков и отложенной публикации.
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePublishing(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  map[string]string
+		draft bool
+		dates map[string]time.Time
+		err   string
+	}{
+		{"без атрибутов", map[string]string{}, false, map[string]time.Time{}, ""},
+		{"даты и черновик", map[string]string{"date": "2025-07-01", "publishDate": "01.08.2025", "expiryDate": " 2026-01-01 12:30 ", "draft": "true"},
+			true, map[string]time.Time{
+				"date":        time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+				"publishDate": time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+				"expiryDate":  time.Date(2026, 1, 1, 12, 30, 0, 0, time.UTC),
+			}, ""},
+		{"не черновик", map[string]string{"draft": "false"}, false, map[string]time.Time{}, ""},
+		{"недопустимая дата", map[string]string{"date": "вчера"}, false, nil, `Недопустимая дата date = "вчера" страницы p`},
+		{"недопустимый draft", map[string]string{"draft": "maybe"}, false, nil, `Недопустимое значение draft = "maybe" страницы p`},
+	}
+	for _, tt := range tests {
+		model := &Model{ID: "p", Data: tt.data, Dates: make(map[string]time.Time)}
+		err := parsePublishing(model)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if model.Draft != tt.draft {
+			t.Errorf("%s: draft = %v, ожидалось %v", tt.name, model.Draft, tt.draft)
+		}
+		if len(model.Dates) != len(tt.dates) {
+			t.Errorf("%s: даты %v, ожидались %v", tt.name, model.Dates, tt.dates)
+		}
+		for attr, want := range tt.dates {
+			if got := model.Dates[attr]; !got.Equal(want) {
+				t.Errorf("%s: %s = %v, ожидалось %v", tt.name, attr, got, want)
+			}
+		}
+	}
+}
+
+func TestPublished(t *testing.T) {
+	now := time.Date(2025, 7, 15, 12, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2025, 7, d, 12, 0, 0, 0, time.UTC) }
+	page := func(draft bool, dates map[string]time.Time) *Model {
+		return &Model{ID: "p", Draft: draft, Dates: dates}
+	}
+	tests := []struct {
+		name   string
+		model  *Model
+		drafts bool
+		future bool
+		want   bool
+	}{
+		{"обычная страница", page(false, map[string]time.Time{}), false, false, true},
+		{"прошлая дата", page(false, map[string]time.Time{"date": day(1)}), false, false, true},
+		{"черновик", page(true, map[string]time.Time{}), false, false, false},
+		{"черновик с -drafts", page(true, map[string]time.Time{}), true, false, true},
+		{"дата в будущем", page(false, map[string]time.Time{"date": day(20)}), false, false, false},
+		{"дата в будущем с -future", page(false, map[string]time.Time{"date": day(20)}), false, true, true},
+		{"publishDate важнее date", page(false, map[string]time.Time{"date": day(1), "publishDate": day(20)}), false, false, false},
+		{"publishDate в прошлом", page(false, map[string]time.Time{"date": day(20), "publishDate": day(1)}), false, false, true},
+		{"публикация ровно сейчас", page(false, map[string]time.Time{"publishDate": now}), false, false, true},
+		{"устаревшая", page(false, map[string]time.Time{"expiryDate": day(10)}), true, true, false},
+		{"устаревает ровно сейчас", page(false, map[string]time.Time{"expiryDate": now}), false, false, false},
+		{"ещё не устарела", page(false, map[string]time.Time{"expiryDate": day(20)}), false, false, true},
+		{"черновик в будущем только с -drafts", page(true, map[string]time.Time{"date": day(20)}), true, false, false},
+		{"черновик в будущем с обоими флагами", page(true, map[string]time.Time{"date": day(20)}), true, true, true},
+	}
+	for _, tt := range tests {
+		opts := &Options{Drafts: tt.drafts, Future: tt.future}
+		if got := opts.published(tt.model, now); got != tt.want {
+			t.Errorf("%s: published = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Сводный отчёт об ошибках сборки.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Стадии сборки, на которых может возникнуть ошибка
+const (
+	stageBlocks   = "blocks"
+	stageContent  = "content"
+	stagePage     = "page"
+	stageTemplate = "template"
+	stageRender   = "render"
+	stageWrite    = "write"
+	stageCategory = "category"
+	stageSitemap  = "sitemap"
+	stageAsset    = "asset"
+)
+
+// Сообщения отчёта
+const (
+	msgReportErrors   = "Ошибки сборки (%d):\n"
+	msgReportWarnings = "Предупреждения (%d):\n"
+	msgReportItem     = "  [%s] %s: %s\n"
+	msgReportSummary  = "Итоги: страниц %d, сгенерировано %d, без изменений %d, исключено %d, терминов %d, скопировано файлов %d, ошибок %d, предупреждений %d\n"
+	msgReportAborted  = "Сборка остановлена после первой ошибки (-fail-fast)"
+	msgErrorReport    = "Ошибка при записи отчёта %s: %v\n"
+)
+
+// BuildIssue описывает одну ошибку или предупреждение сборки
+type BuildIssue struct {
+	Stage string `json:"stage"`
+	Item  string `json:"item,omitempty"` // Идентификатор страницы, категория или путь к файлу
+	Cause string `json:"cause"`
+}
+
+// BuildReport собирает ошибки всех стадий сборки. Методы безопасны для вызова из нескольких горутин.
+type BuildReport struct {
+	Pages      int            `json:"pages"`
+	Generated  int            `json:"generated"`
+	Skipped    int            `json:"skipped"`    // Страницы, не изменившиеся с прошлой сборки
+	Excluded   int            `json:"excluded"`   // Черновики, отложенные и устаревшие страницы
+	Categories int            `json:"categories"` // Термины всех таксономий, включая категории
+	Assets     int            `json:"assets"`     // Скопированные статические файлы
+	Errors     []BuildIssue   `json:"errors"`
+	Warnings   []BuildIssue   `json:"warnings"`
+	ByStage    map[string]int `json:"errorsByStage"`
+	Aborted    bool           `json:"aborted"`
+	DurationMs int64          `json:"durationMs"`
+
+	mu       sync.Mutex
+	failFast bool
+	stop     int32
+}
+
+// newBuildReport создаёт пустой отчёт. При failFast первая ошибка останавливает сборку.
+func newBuildReport(failFast bool) *BuildReport {
+	return &BuildReport{
+		Errors:   make([]BuildIssue, 0),
+		Warnings: make([]BuildIssue, 0),
+		ByStage:  make(map[string]int),
+		failFast: failFast,
+	}
+}
+
+// addError регистрирует ошибку стадии stage для элемента item
+func (r *BuildReport) addError(stage, item string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Errors = append(r.Errors, BuildIssue{Stage: stage, Item: item, Cause: err.Error()})
+	r.ByStage[stage]++
+	if r.failFast {
+		r.Aborted = true
+		atomic.StoreInt32(&r.stop, 1)
+	}
+}
+
+// addWarning регистрирует предупреждение, которое не делает сборку неуспешной
+func (r *BuildReport) addWarning(stage, item, cause string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, BuildIssue{Stage: stage, Item: item, Cause: cause})
+}
+
+// pageGenerated увеличивает счётчик сгенерированных страниц
+func (r *BuildReport) pageGenerated() {
+	r.mu.Lock()
+	r.Generated++
+	r.mu.Unlock()
+}
+
+// pageSkipped увеличивает счётчик страниц, пропущенных без изменений
+func (r *BuildReport) pageSkipped() {
+	r.mu.Lock()
+	r.Skipped++
+	r.mu.Unlock()
+}
+
+// pageExcluded увеличивает счётчик страниц, не попавших в сборку по дате или как черновик
+func (r *BuildReport) pageExcluded() {
+	r.mu.Lock()
+	r.Excluded++
+	r.mu.Unlock()
+}
+
+// assetCopied увеличивает счётчик скопированных статических файлов
+func (r *BuildReport) assetCopied() {
+	r.mu.Lock()
+	r.Assets++
+	r.mu.Unlock()
+}
+
+// stopped сообщает, что сборку нужно прекратить из-за ошибки в режиме -fail-fast
+func (r *BuildReport) stopped() bool {
+	return atomic.LoadInt32(&r.stop) == 1
+}
+
+// Failed сообщает, была ли в сборке хотя бы одна ошибка
+func (r *BuildReport) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Errors) > 0
+}
+
+// finish фиксирует длительность сборки и упорядочивает ошибки для стабильного вывода
+func (r *BuildReport) finish(start time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.DurationMs = time.Since(start).Milliseconds()
+	for _, issues := range [][]BuildIssue{r.Errors, r.Warnings} {
+		sort.SliceStable(issues, func(i, j int) bool {
+			if issues[i].Stage != issues[j].Stage {
+				return issues[i].Stage < issues[j].Stage
+			}
+			return issues[i].Item < issues[j].Item
+		})
+	}
+}
+
+// print выводит ошибки, предупреждения и итоговую статистику
+func (r *BuildReport) print(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Warnings) > 0 {
+		fmt.Fprintf(w, msgReportWarnings, len(r.Warnings))
+		for _, issue := range r.Warnings {
+			fmt.Fprintf(w, msgReportItem, issue.Stage, issue.Item, issue.Cause)
+		}
+	}
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(w, msgReportErrors, len(r.Errors))
+		for _, issue := range r.Errors {
+			fmt.Fprintf(w, msgReportItem, issue.Stage, issue.Item, issue.Cause)
+		}
+	}
+	if r.Aborted {
+		fmt.Fprintln(w, msgReportAborted)
+	}
+	fmt.Fprintf(w, msgReportSummary, r.Pages, r.Generated, r.Skipped, r.Excluded, r.Categories, r.Assets, len(r.Errors), len(r.Warnings))
+}
+
+// writeJSON сохраняет отчёт в формате JSON в файл path; "-" означает стандартный вывод
+func (r *BuildReport) writeJSON(path string) error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,147 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты сводного отчёта о сборке.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBuildReportAggregatesErrors(t *testing.T) {
+	report := newBuildReport(false)
+	var wg sync.WaitGroup
+	for _, issue := range []BuildIssue{
+		{stageTemplate, "b", "нет шаблона"},
+		{stageRender, "a", "ошибка рендеринга"},
+		{stageTemplate, "a", "нет шаблона"},
+		{stageCategory, "go", "путь занят"},
+	} {
+		wg.Add(1)
+		go func(issue BuildIssue) {
+			defer wg.Done()
+			report.addError(issue.Stage, issue.Item, errors.New(issue.Cause))
+		}(issue)
+	}
+	wg.Wait()
+	report.addWarning(stageContent, "c", "устаревшее поле")
+	report.finish(time.Now())
+
+	if !report.Failed() || report.stopped() || report.Aborted {
+		t.Errorf("Failed %v, stopped %v, Aborted %v", report.Failed(), report.stopped(), report.Aborted)
+	}
+	// После finish ошибки упорядочены по стадии и элементу
+	want := []BuildIssue{
+		{stageCategory, "go", "путь занят"},
+		{stageRender, "a", "ошибка рендеринга"},
+		{stageTemplate, "a", "нет шаблона"},
+		{stageTemplate, "b", "нет шаблона"},
+	}
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Errorf("ошибки %+v, ожидались %+v", report.Errors, want)
+	}
+	if byStage := map[string]int{stageCategory: 1, stageRender: 1, stageTemplate: 2}; !reflect.DeepEqual(report.ByStage, byStage) {
+		t.Errorf("по стадиям %v, ожидалось %v", report.ByStage, byStage)
+	}
+
+	// Предупреждения не делают сборку неуспешной
+	warned := newBuildReport(false)
+	warned.addWarning(stageContent, "c", "устаревшее поле")
+	if warned.Failed() {
+		t.Error("сборка с предупреждением считается неуспешной")
+	}
+}
+
+func TestBuildReportFailFast(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepGoing bool
+		failFast  bool
+		stop      bool
+	}{
+		{"-keep-going", true, false, false},
+		{"-fail-fast", true, true, true},
+		{"-keep-going=false", false, false, true},
+	}
+	for _, tt := range tests {
+		opts := &Options{KeepGoing: tt.keepGoing, FailFast: tt.failFast}
+		report := newBuildReport(opts.stopOnError())
+		if report.stopped() {
+			t.Errorf("%s: сборка остановлена до первой ошибки", tt.name)
+		}
+		report.addError(stagePage, "a", errors.New("x"))
+		if report.stopped() != tt.stop || report.Aborted != tt.stop {
+			t.Errorf("%s: stopped %v, Aborted %v, ожидалось %v", tt.name, report.stopped(), report.Aborted, tt.stop)
+		}
+		// Последующие ошибки по-прежнему попадают в отчёт
+		report.addError(stagePage, "b", errors.New("y"))
+		if len(report.Errors) != 2 {
+			t.Errorf("%s: ошибок %d, ожидалось 2", tt.name, len(report.Errors))
+		}
+	}
+}
+
+func TestBuildReportJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	empty := newBuildReport(false)
+	if err := empty.writeJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	read := func() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = nil
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%v: %s", err, data)
+		}
+	}
+	read()
+	// Пустые списки записываются как [], а не null
+	for key, want := range map[string]interface{}{
+		"pages": 0.0, "generated": 0.0, "skipped": 0.0, "excluded": 0.0, "categories": 0.0, "assets": 0.0,
+		"errors": []interface{}{}, "warnings": []interface{}{}, "errorsByStage": map[string]interface{}{},
+		"aborted": false, "durationMs": 0.0,
+	} {
+		if value, ok := got[key]; !ok || !reflect.DeepEqual(value, want) {
+			t.Errorf("%s = %#v, ожидалось %#v", key, value, want)
+		}
+	}
+	if len(got) != 11 {
+		t.Errorf("лишние поля отчёта: %v", got)
+	}
+
+	report := newBuildReport(true)
+	report.Pages, report.Generated = 3, 2
+	report.addError(stageBlocks, "", errors.New("цикл"))
+	report.addWarning(stageContent, "a", "устаревшее поле")
+	if err := report.writeJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	read()
+	checks := map[string]interface{}{
+		"pages":         3.0,
+		"generated":     2.0,
+		"aborted":       true,
+		"errors":        []interface{}{map[string]interface{}{"stage": "blocks", "cause": "цикл"}},
+		"warnings":      []interface{}{map[string]interface{}{"stage": "content", "item": "a", "cause": "устаревшее поле"}},
+		"errorsByStage": map[string]interface{}{"blocks": 1.0},
+	}
+	for key, want := range checks {
+		if !reflect.DeepEqual(got[key], want) {
+			t.Errorf("%s = %#v, ожидалось %#v", key, got[key], want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: HTTP-сервер для разработки: чистые адреса и автоматическая перезагрузка страниц.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// reloadPath — адрес потока событий, по которому браузер узнаёт о пересборке
+const reloadPath = "/__goferret/reload"
+
+// reloadScript подключается к потоку событий и перезагружает страницу после пересборки
+const reloadScript = `<script>(function(){var s=new EventSource("` + reloadPath + `");` +
+	`s.addEventListener("reload",function(){location.reload()});})();</script>`
+
+// reloadHub рассылает событие перезагрузки всем открытым страницам
+type reloadHub struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+}
+
+func newReloadHub() *reloadHub {
+	return &reloadHub{clients: make(map[chan struct{}]bool)}
+}
+
+// broadcast сообщает всем подключённым браузерам, что сайт пересобран
+func (h *reloadHub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		// Браузеру достаточно одного события, даже если пересборок было несколько
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP держит поток Server-Sent Events открытым, пока страница не закрыта
+func (h *reloadHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.clients[ch] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, ch)
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// devServer раздаёт директорию сборки. Адреса без расширения находят <path>.html
+// или <path>/index.html, а в HTML-страницы встраивается скрипт перезагрузки.
+type devServer struct {
+	mu     sync.RWMutex
+	root   string     // Директория сборки; меняется, если конфигурация задала другую build.output
+	reload *reloadHub // nil, если перезагрузка отключена
+}
+
+// setRoot задаёт раздаваемую директорию
+func (s *devServer) setRoot(root string) {
+	s.mu.Lock()
+	s.root = root
+	s.mu.Unlock()
+}
+
+func (s *devServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.reload != nil && r.URL.Path == reloadPath {
+		s.reload.ServeHTTP(w, r)
+		return
+	}
+
+	urlPath := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") && urlPath != "/" {
+		urlPath += "/"
+	}
+	// Служебные файлы сборки, например манифест, не раздаются
+	for _, segment := range strings.Split(urlPath, "/") {
+		if strings.HasPrefix(segment, ".") {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	file, redirect := s.resolve(urlPath)
+	if redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusMovedPermanently)
+		return
+	}
+	if file == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if s.reload == nil || filepath.Ext(file) != ".html" {
+		http.ServeFile(w, r, file)
+		return
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, file, info.ModTime(), bytes.NewReader(injectReloadScript(data)))
+}
+
+// resolve находит файл для адреса urlPath. Для директории со страницей index.html
+// без завершающего "/" возвращается адрес перенаправления, чтобы относительные ссылки работали.
+func (s *devServer) resolve(urlPath string) (file, redirect string) {
+	isFile := func(p string) bool {
+		info, err := os.Stat(p)
+		return err == nil && !info.IsDir()
+	}
+	s.mu.RLock()
+	root := s.root
+	s.mu.RUnlock()
+	base := filepath.Join(root, filepath.FromSlash(urlPath))
+	index := filepath.Join(base, "index.html")
+
+	switch {
+	case strings.HasSuffix(urlPath, "/"):
+		if isFile(index) {
+			return index, ""
+		}
+	case isFile(base):
+		return base, ""
+	case isFile(base + ".html"):
+		return base + ".html", ""
+	case isFile(index):
+		return "", urlPath + "/"
+	}
+	return "", ""
+}
+
+// injectReloadScript вставляет скрипт перезагрузки перед </body> или в конец документа
+func injectReloadScript(page []byte) []byte {
+	const closeBody = "</body>"
+	i := len(page)
+	for j := bytes.LastIndex(page, []byte("</")); j >= 0; j = bytes.LastIndex(page[:j], []byte("</")) {
+		if len(page)-j >= len(closeBody) && strings.EqualFold(string(page[j:j+len(closeBody)]), closeBody) {
+			i = j
+			break
+		}
+	}
+	out := make([]byte, 0, len(page)+len(reloadScript))
+	out = append(out, page[:i]...)
+	out = append(out, reloadScript...)
+	return append(out, page[i:]...)
+}
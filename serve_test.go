@@ -0,0 +1,103 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты сервера разработки: чистые адреса, служебные файлы и скрипт перезагрузки.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDevServer(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":              "<html><body>home</body></html>",
+		"about.html":              "<html><body>about</body></html>",
+		"docs/index.html":         "<html><BODY>docs</BODY></html>",
+		"docs/setup/index.html":   "<p>setup</p>",
+		"css/site.css":            "body{}",
+		".goferret-manifest.json": "{}",
+		".git/config":             "x",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := &devServer{root: root, reload: newReloadHub()}
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		body   string // Ожидаемое тело; для перенаправления — адрес Location
+	}{
+		{"корень", "/", http.StatusOK, "<html><body>home" + reloadScript + "</body></html>"},
+		{"адрес без .html", "/about", http.StatusOK, "<html><body>about" + reloadScript + "</body></html>"},
+		{"адрес с .html", "/about.html", http.StatusOK, "<html><body>about" + reloadScript + "</body></html>"},
+		{"директория со слешем", "/docs/", http.StatusOK, "<html><BODY>docs" + reloadScript + "</BODY></html>"},
+		{"директория без слеша", "/docs/setup", http.StatusMovedPermanently, "/docs/setup/"},
+		{"страница без </body>", "/docs/setup/", http.StatusOK, "<p>setup</p>" + reloadScript},
+		{"не HTML", "/css/site.css", http.StatusOK, "body{}"},
+		{"манифест сборки", "/.goferret-manifest.json", http.StatusNotFound, ""},
+		{"скрытая директория", "/.git/config", http.StatusNotFound, ""},
+		{"выход за пределы сборки", "/../about.html", http.StatusOK, "<html><body>about" + reloadScript + "</body></html>"},
+		{"нет файла", "/missing", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: статус %d, ожидался %d", tt.name, rec.Code, tt.status)
+			continue
+		}
+		switch {
+		case tt.status == http.StatusMovedPermanently:
+			if got := rec.Header().Get("Location"); got != tt.body {
+				t.Errorf("%s: перенаправление на %q, ожидалось %q", tt.name, got, tt.body)
+			}
+		case tt.status == http.StatusOK:
+			if got := rec.Body.String(); got != tt.body {
+				t.Errorf("%s: получено %q, ожидалось %q", tt.name, got, tt.body)
+			}
+		}
+	}
+
+	// Без перезагрузки HTML раздаётся как есть
+	server.reload = nil
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/about", nil))
+	if got := rec.Body.String(); got != files["about.html"] {
+		t.Errorf("без перезагрузки: получено %q", got)
+	}
+}
+
+func TestInjectReloadScript(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{"перед </body>", "<body>x</body></html>", "<body>x" + reloadScript + "</body></html>"},
+		{"регистр тега", "<BODY>x</Body>", "<BODY>x" + reloadScript + "</Body>"},
+		{"последний </body>", "<body><pre></body></pre></body>", "<body><pre></body></pre>" + reloadScript + "</body>"},
+		{"без </body>", "<p>x</p>", "<p>x</p>" + reloadScript},
+		{"пустая страница", "", reloadScript},
+	}
+	for _, tt := range tests {
+		if got := string(injectReloadScript([]byte(tt.page))); got != tt.want {
+			t.Errorf("%s: получено %q, ожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Карта сайта sitemap.xml и файл robots.txt.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sitemapMaxURLs — предел протокола Sitemaps для одного файла. Сайт с большим числом страниц
+// получает несколько файлов sitemap-<N>.xml и указатель sitemap.xml.
+const sitemapMaxURLs = 50000
+
+// Файлы карты сайта и её ключ в манифесте сборки
+const (
+	sitemapFile = "sitemap.xml"
+	sitemapNS   = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	robotsFile  = "robots.txt"
+	sitemapKey  = "/sitemap"
+)
+
+// sitemapURL — адрес в карте сайта или файл карты в указателе
+type sitemapURL struct {
+	Loc      string
+	LastMod  time.Time // Нулевое время, если дата неизвестна
+	Priority string
+}
+
+// collectionFile — файл, подготовленный в памяти перед записью в директорию сборки
+type collectionFile struct {
+	Name    string
+	Content []byte
+}
+
+// pageLastMod возвращает дату изменения страницы: атрибут lastmod, затем date,
+// а без них — время изменения её исходных файлов
+func pageLastMod(model *Model) time.Time {
+	for _, attr := range []string{"lastmod", "date"} {
+		if t, ok := model.Dates[attr]; ok {
+			return t
+		}
+	}
+	return model.ModTime
+}
+
+// pageNoIndex сообщает, что страница исключена из карты сайта атрибутом noindex = true.
+// Недопустимое значение передаётся в warn и не учитывается.
+func pageNoIndex(model *Model, warn func(id, cause string)) bool {
+	value := strings.TrimSpace(model.Data["noindex"])
+	if value == "" {
+		return false
+	}
+	noindex, err := strconv.ParseBool(value)
+	if err != nil {
+		warn(model.ID, fmt.Sprintf("noindex = %q: ожидается true или false", value))
+		return false
+	}
+	return noindex
+}
+
+// sitemapURLs возвращает адреса страниц для карты сайта. Страницы с noindex = true пропускаются,
+// priority задаёт приоритет от 0 до 1. Недопустимые значения передаются в warn и не учитываются.
+func sitemapURLs(models []*Model, base string, warn func(id, cause string)) []sitemapURL {
+	urls := make([]sitemapURL, 0, len(models))
+	for _, model := range models {
+		if pageNoIndex(model, warn) {
+			continue
+		}
+		u := sitemapURL{Loc: absoluteURL(base, model.URL), LastMod: pageLastMod(model)}
+		if value := strings.TrimSpace(model.Data["priority"]); value != "" {
+			p, err := strconv.ParseFloat(value, 64)
+			if err != nil || p < 0 || p > 1 {
+				warn(model.ID, fmt.Sprintf("priority = %q: ожидается число от 0 до 1", value))
+			} else {
+				u.Priority = strconv.FormatFloat(p, 'f', -1, 64)
+			}
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// termSitemapURLs возвращает адреса первых страниц терминов и указателей терминов из tasks.
+// Термин попадает в карту, если среди его страниц есть хотя бы одна без noindex, а указатель —
+// если он записывается в HTML и в карте есть хотя бы один его термин. lastmod — самая поздняя
+// дата среди страниц термина или терминов указателя.
+func termSitemapURLs(tasks []TermTask, base string, prettyURLs bool) []sitemapURL {
+	ignore := func(id, cause string) {} // Предупреждения уже выданы для страниц
+	var urls []sitemapURL
+	indexed := make(map[*taxonomyTerm]time.Time)
+	for _, task := range tasks {
+		if task.Taxonomy == nil || task.Term == nil {
+			continue
+		}
+		var lastMod time.Time
+		listed := false
+		for _, model := range task.Term.Pages {
+			if pageNoIndex(model, ignore) {
+				continue
+			}
+			listed = true
+			if t := pageLastMod(model); t.After(lastMod) {
+				lastMod = t
+			}
+		}
+		if !listed {
+			continue
+		}
+		indexed[task.Term] = lastMod
+		_, url := termPagePath(task.Term.Base, 1, prettyURLs)
+		urls = append(urls, sitemapURL{Loc: absoluteURL(base, url), LastMod: lastMod})
+	}
+	for _, task := range tasks {
+		if task.Taxonomy == nil || task.Term != nil || task.Template == nil {
+			continue
+		}
+		var lastMod time.Time
+		listed := false
+		for _, term := range task.Terms {
+			if t, ok := indexed[term]; ok {
+				listed = true
+				if t.After(lastMod) {
+					lastMod = t
+				}
+			}
+		}
+		if listed {
+			urls = append(urls, sitemapURL{Loc: absoluteURL(base, "/"+task.Taxonomy.Name+"/"), LastMod: lastMod})
+		}
+	}
+	return urls
+}
+
+// renderSitemap формирует файл карты сайта (element = "url") или указатель (element = "sitemap").
+// XML пишется напрямую, а не через encoding/xml: на сайтах в сотни тысяч страниц это заметно быстрее.
+func renderSitemap(root, element string, urls []sitemapURL) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(urls) * 128)
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, "<%s xmlns=\"%s\">\n", root, sitemapNS)
+	for _, u := range urls {
+		buf.WriteString("  <" + element + ">\n    <loc>")
+		xml.EscapeText(&buf, []byte(u.Loc))
+		buf.WriteString("</loc>\n")
+		if !u.LastMod.IsZero() {
+			buf.WriteString("    <lastmod>" + u.LastMod.UTC().Format(time.RFC3339) + "</lastmod>\n")
+		}
+		if u.Priority != "" {
+			buf.WriteString("    <priority>" + u.Priority + "</priority>\n")
+		}
+		buf.WriteString("  </" + element + ">\n")
+	}
+	buf.WriteString("</" + root + ">\n")
+	return buf.Bytes()
+}
+
+// sitemapFiles раскладывает адреса по файлам карты сайта. До sitemapMaxURLs адресов — один
+// sitemap.xml, больше — sitemap-1.xml, sitemap-2.xml и указатель на них в sitemap.xml.
+func sitemapFiles(urls []sitemapURL, base string) []collectionFile {
+	if len(urls) <= sitemapMaxURLs {
+		return []collectionFile{{Name: sitemapFile, Content: renderSitemap("urlset", "url", urls)}}
+	}
+	var files []collectionFile
+	var refs []sitemapURL
+	for start := 0; start < len(urls); start += sitemapMaxURLs {
+		end := start + sitemapMaxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+		name := fmt.Sprintf("sitemap-%d.xml", len(files)+1)
+		ref := sitemapURL{Loc: base + "/" + name}
+		for _, u := range urls[start:end] {
+			if u.LastMod.After(ref.LastMod) {
+				ref.LastMod = u.LastMod
+			}
+		}
+		files = append(files, collectionFile{Name: name, Content: renderSitemap("urlset", "url", urls[start:end])})
+		refs = append(refs, ref)
+	}
+	return append(files, collectionFile{Name: sitemapFile, Content: renderSitemap("sitemapindex", "sitemap", refs)})
+}
+
+// robotsTxt разрешает обход всего сайта и указывает на карту сайта
+func robotsTxt(base string) []byte {
+	return []byte("User-agent: *\nDisallow:\n\nSitemap: " + base + "/" + sitemapFile + "\n")
+}
+
+// sitemapPlan возвращает файлы карты сайта по страницам models и страницам терминов из terms и, если
+// включено sitemap.robots, robots.txt и закрепляет их пути в owners. Без site.baseURL карта не пишется,
+// потому что адреса в ней должны быть абсолютными. Если путь карты уже занят страницей, файлом static/
+// или термином, карта не пишется, а конфликт попадает в отчёт как ошибка.
+func sitemapPlan(models []*Model, terms []TermTask, opts *Options, report *BuildReport, owners map[string]string) []collectionFile {
+	base := siteBaseURL(opts.Config)
+	if !opts.Config.Sitemap.Enabled || base == "" {
+		return nil
+	}
+	urls := sitemapURLs(models, base, func(id, cause string) {
+		report.addWarning(stageSitemap, id, cause)
+	})
+	urls = append(urls, termSitemapURLs(terms, base, opts.Config.Build.PrettyURLs)...)
+	files := sitemapFiles(urls, base)
+	for _, file := range files {
+		if owner, taken := owners[file.Name]; taken {
+			report.addError(stageSitemap, file.Name, fmt.Errorf("путь %s уже занят: %s", file.Name, owner))
+			return nil
+		}
+	}
+	// Собственный robots.txt из static/ важнее сгенерированного
+	if _, own := owners[robotsFile]; opts.Config.Sitemap.Robots && !own {
+		files = append(files, collectionFile{Name: robotsFile, Content: robotsTxt(base)})
+	}
+	for _, file := range files {
+		owners[file.Name] = "карта сайта"
+	}
+	return files
+}
+
+// generateSitemap записывает файлы карты сайта из sitemapPlan. Файлы, не изменившиеся
+// с предыдущей сборки (prev), не перезаписываются.
+func generateSitemap(models []*Model, terms []TermTask, opts *Options, report *BuildReport, prev, next *BuildManifest, owners map[string]string) {
+	files := sitemapPlan(models, terms, opts, report, owners)
+	if len(files) == 0 {
+		return
+	}
+
+	var fingerprint strings.Builder
+	outputs := make([]string, 0, len(files))
+	for _, file := range files {
+		fingerprint.WriteString(file.Name + "\x00" + contentHash(string(file.Content)) + "\x00")
+		outputs = append(outputs, file.Name)
+	}
+	entry := &ManifestCategory{Fingerprint: contentHash(fingerprint.String()), Outputs: outputs}
+	if old := prev.Categories[sitemapKey]; old != nil && old.Fingerprint == entry.Fingerprint && outputsExist(opts.OutputDir, old.Outputs) {
+		next.setCategory(sitemapKey, old)
+		return
+	}
+
+	for _, file := range files {
+		if err := writeCollectionFile(opts, file.Name, file.Content); err != nil {
+			report.addError(stageSitemap, file.Name, err)
+			// Без отпечатка карта запишется заново в следующий раз, а прежние файлы не удаляются
+			if old := prev.Categories[sitemapKey]; old != nil {
+				outputs = append(outputs, old.Outputs...)
+			}
+			next.setCategory(sitemapKey, &ManifestCategory{Outputs: outputs})
+			return
+		}
+	}
+	next.setCategory(sitemapKey, entry)
+}
@@ -0,0 +1,120 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты адресов карты сайта.
+*/
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTermSitemapURLs(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 7, d, 0, 0, 0, 0, time.UTC) }
+	page := func(id string, d int, noindex string) *Model {
+		return &Model{ID: id, Data: map[string]string{"noindex": noindex}, Dates: map[string]time.Time{"date": day(d)}}
+	}
+	tags := &taxonomy{Name: "tags"}
+	goTerm := &taxonomyTerm{Name: "go", Base: "tags/go", Pages: []*Model{page("a", 1, ""), page("b", 3, "false"), page("c", 5, "true")}}
+	hidden := &taxonomyTerm{Name: "hidden", Base: "tags/hidden", Pages: []*Model{page("c", 5, "true")}}
+	drafts := &taxonomy{Name: "drafts"}
+	draftTerm := &taxonomyTerm{Name: "x", Base: "drafts/x", Pages: []*Model{page("c", 5, "true")}}
+	tpl, err := compileTemplate("terms", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := []TermTask{
+		{Taxonomy: tags, Term: goTerm, Kept: true},
+		{Taxonomy: tags, Term: hidden},
+		{Taxonomy: tags, Terms: []*taxonomyTerm{goTerm, hidden}, Template: tpl},
+		{Taxonomy: drafts, Term: draftTerm},
+		{Taxonomy: drafts, Terms: []*taxonomyTerm{draftTerm}, Template: tpl},
+		{Key: siteFeedKey, Feed: &siteFeed{}},
+	}
+	want := []sitemapURL{
+		{Loc: "https://x.com/tags/go/", LastMod: day(3)},
+		{Loc: "https://x.com/tags/", LastMod: day(3)},
+	}
+	if got := termSitemapURLs(tasks, "https://x.com", true); !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %+v, ожидалось %+v", got, want)
+	}
+
+	// Без шаблона указатель записывается только в JSON и в карту не попадает
+	tasks[2].Template = nil
+	want = want[:1]
+	want[0].Loc = "https://x.com/tags/go.html"
+	if got := termSitemapURLs(tasks, "https://x.com", false); !reflect.DeepEqual(got, want) {
+		t.Errorf("без шаблона указателя: получено %+v, ожидалось %+v", got, want)
+	}
+}
+
+func TestSitemapURLsEncodePaths(t *testing.T) {
+	models := []*Model{
+		{ID: "заметки/a b", URL: "/заметки/a b.html", Data: map[string]string{}},
+		{ID: "index", URL: "/", Data: map[string]string{}},
+	}
+	var got []string
+	for _, u := range sitemapURLs(models, "https://x.com", func(id, cause string) {}) {
+		got = append(got, u.Loc)
+	}
+	category := &taxonomy{Name: "рубрики", legacy: true}
+	term := &taxonomyTerm{Name: "новости", Base: "новости", Pages: models[:1]}
+	tasks := []TermTask{
+		{Taxonomy: category, Term: term},
+		{Taxonomy: category, Terms: []*taxonomyTerm{term}, Template: &Template{}},
+	}
+	for _, u := range termSitemapURLs(tasks, "https://x.com", true) {
+		got = append(got, u.Loc)
+	}
+	want := []string{
+		"https://x.com/%D0%B7%D0%B0%D0%BC%D0%B5%D1%82%D0%BA%D0%B8/a%20b.html",
+		"https://x.com/",
+		"https://x.com/%D0%BD%D0%BE%D0%B2%D0%BE%D1%81%D1%82%D0%B8/",
+		"https://x.com/%D1%80%D1%83%D0%B1%D1%80%D0%B8%D0%BA%D0%B8/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %q, ожидалось %q", got, want)
+	}
+}
+
+func TestSitemapPlanClaimsOutputs(t *testing.T) {
+	opts := &Options{Config: defaultConfig()}
+	opts.Config.Site["baseURL"] = "https://x.com"
+	models := []*Model{{ID: "a", URL: "/a.html", Data: map[string]string{}}}
+
+	tests := []struct {
+		name   string
+		owners map[string]string
+		files  string // Записываемые файлы через запятую
+		err    string // Фрагмент ошибки; пустой — конфликтов нет
+	}{
+		{"свободные пути", map[string]string{"a.html": "страница a"}, "sitemap.xml,robots.txt", ""},
+		{"собственный robots.txt", map[string]string{"robots.txt": "static/robots.txt"}, "sitemap.xml", ""},
+		{"sitemap.xml из static", map[string]string{"sitemap.xml": "static/sitemap.xml"}, "",
+			"путь sitemap.xml уже занят: static/sitemap.xml"},
+	}
+	for _, tt := range tests {
+		report := newBuildReport(false)
+		var names []string
+		for _, file := range sitemapPlan(models, nil, opts, report, tt.owners) {
+			names = append(names, file.Name)
+			if tt.owners[file.Name] != "карта сайта" {
+				t.Errorf("%s: путь %s не закреплён: %v", tt.name, file.Name, tt.owners)
+			}
+		}
+		if got := strings.Join(names, ","); got != tt.files {
+			t.Errorf("%s: файлы %q, ожидались %q", tt.name, got, tt.files)
+		}
+		switch {
+		case tt.err == "" && len(report.Errors) > 0:
+			t.Errorf("%s: ошибки %+v", tt.name, report.Errors)
+		case tt.err != "" && (len(report.Errors) != 1 || !strings.Contains(report.Errors[0].Cause, tt.err)):
+			t.Errorf("%s: ошибки %+v, ожидалась содержащая %q", tt.name, report.Errors, tt.err)
+		}
+	}
+}
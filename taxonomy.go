@@ -0,0 +1,806 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Таксономии: группировка страниц по категориям, тегам, авторам и другим атрибутам.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// categoryTaxonomy — встроенная таксономия category.val, файлы которой лежат в корне сборки
+const categoryTaxonomy = "category"
+
+// taxonomy описывает группировку страниц по значениям атрибута
+type taxonomy struct {
+	Name   string // Имя таксономии и директория её страниц в сборке
+	Key    string // Атрибут страницы со списком терминов
+	SortBy string // Ключ сортировки элементов: id или атрибут страницы
+	Desc   bool   // Сортировка по убыванию
+	Fields []itemField
+	legacy bool // category: один термин из Model.Category, страницы в корне сборки
+}
+
+// taxonomyTerm — один термин таксономии и его страницы
+type taxonomyTerm struct {
+	Name  string
+	Base  string // Путь первой страницы термина без расширения, например tags/go
+	Items []map[string]interface{}
+	Pages []*Model // Страницы термина в порядке элементов, для лент
+}
+
+// TermTask описывает страницы одного термина, указатель терминов таксономии (Term == nil)
+// или ленту всего сайта (Taxonomy == nil)
+type TermTask struct {
+	Taxonomy    *taxonomy
+	Term        *taxonomyTerm
+	Terms       []*taxonomyTerm
+	Template    *Template
+	Feed        *siteFeed // Лента термина или сайта; nil, если ленты отключены
+	Key         string    // Ключ записи в манифесте сборки
+	Fingerprint string    // Отпечаток элементов и шаблона для манифеста сборки
+	Kept        bool      // Результат не изменился с предыдущей сборки и не перезаписывается
+}
+
+// siteTaxonomies возвращает встроенную таксономию category и таксономии из конфигурации.
+// Порядок элементов берётся из настроек таксономии, затем из build.sortBy и build.sortOrder.
+func siteTaxonomies(cfg *SiteConfig) []*taxonomy {
+	withOrder := func(t *taxonomy, sortBy, sortOrder string, fields []string) *taxonomy {
+		if fields == nil {
+			fields = cfg.Build.ItemFields
+		}
+		// Поля проверены при чтении конфигурации
+		t.Fields, _ = parseItemFields(fields)
+		t.SortBy, t.Desc = cfg.Build.SortBy, cfg.Build.SortOrder == "desc"
+		if sortBy != "" {
+			t.SortBy = sortBy
+		}
+		if sortOrder != "" {
+			t.Desc = sortOrder == "desc"
+		}
+		if t.SortBy == "" {
+			t.SortBy = "id"
+		}
+		return t
+	}
+
+	list := []*taxonomy{withOrder(&taxonomy{Name: categoryTaxonomy, Key: categoryTaxonomy, legacy: true}, "", "", nil)}
+	names := make([]string, 0, len(cfg.Taxonomies))
+	for name := range cfg.Taxonomies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tc := cfg.Taxonomies[name]
+		key := tc.Key
+		if key == "" {
+			key = name
+		}
+		list = append(list, withOrder(&taxonomy{Name: name, Key: key}, tc.SortBy, tc.SortOrder, tc.Fields))
+	}
+	return list
+}
+
+// termsOf возвращает термины страницы. Списки из front matter берутся поэлементно,
+// а строка из .val делится по строкам, если их несколько, иначе — по запятым.
+func (t *taxonomy) termsOf(model *Model) []string {
+	if t.legacy {
+		if model.Category == "" {
+			return nil
+		}
+		return []string{model.Category}
+	}
+	value, _ := lookupFlat(model.Data, t.Key)
+	var terms []string
+	for _, item := range listItems(value) {
+		if term := strings.TrimSpace(formatValue(item)); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// collect группирует страницы по терминам в порядке сортировки таксономии. Термины с одинаковым
+// адресом (Go и go) объединяются под именем, встреченным первым. Возвращает термины, упорядоченные по адресу.
+// Значения полей, которые не удалось привести к типу, передаются в warn, а недопустимые имена категорий — в fail.
+func (t *taxonomy) collect(models []*Model, warn, fail func(id string, err error)) []*taxonomyTerm {
+	byBase := make(map[string]*taxonomyTerm)
+	for _, model := range sortModels(models, t.SortBy, t.Desc) {
+		terms := t.termsOf(model)
+		if len(terms) == 0 {
+			continue
+		}
+		item, errs := t.item(model)
+		for _, err := range errs {
+			warn(model.ID, err)
+		}
+		seen := make(map[string]bool)
+		for _, name := range terms {
+			base, err := t.termBase(name)
+			if err != nil {
+				fail(model.ID, err)
+				continue
+			}
+			if base == "" || seen[base] {
+				continue
+			}
+			seen[base] = true
+			term, exists := byBase[base]
+			if !exists {
+				term = &taxonomyTerm{Name: name, Base: base}
+				byBase[base] = term
+			}
+			term.Items = append(term.Items, item)
+			term.Pages = append(term.Pages, model)
+		}
+	}
+
+	terms := make([]*taxonomyTerm, 0, len(byBase))
+	for _, term := range byBase {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i].Base < terms[j].Base })
+	return terms
+}
+
+// Типы полей элементов списков
+const (
+	fieldString = "string"
+	fieldNumber = "number"
+	fieldDate   = "date"
+	fieldList   = "list"
+)
+
+// readingWordsPerMinute — скорость чтения для вычисляемого поля readingTime
+const readingWordsPerMinute = 200
+
+// itemField — поле элемента списка: атрибут страницы или вычисляемое значение и его тип в JSON
+type itemField struct {
+	Key  string // Имя поля в элементе
+	Name string // Атрибут страницы или вычисляемое поле
+	Type string
+}
+
+// computedFields — поля, которые вычисляются по странице, а не берутся из атрибутов
+var computedFields = map[string]func(model *Model) interface{}{
+	"id":  func(model *Model) interface{} { return model.ID },
+	"url": func(model *Model) interface{} { return model.URL },
+	"wordCount": func(model *Model) interface{} {
+		return wordCount(model.Data["content"])
+	},
+	"readingTime": func(model *Model) interface{} {
+		words := wordCount(model.Data["content"])
+		return (words + readingWordsPerMinute - 1) / readingWordsPerMinute
+	},
+}
+
+// defaultItemFields есть в каждом элементе списка
+var defaultItemFields = []itemField{
+	{Key: "title", Name: "title", Type: fieldString},
+	{Key: "url", Name: "url", Type: fieldString},
+}
+
+// parseItemFields разбирает описания полей вида "summary", "date:date", "tags:list"
+// или "author=author.name" и добавляет их к title и url
+func parseItemFields(specs []string) ([]itemField, error) {
+	fields := append([]itemField(nil), defaultItemFields...)
+	seen := map[string]bool{"title": true, "url": true}
+	for _, spec := range specs {
+		name, typ := spec, fieldString
+		if i := strings.LastIndex(spec, ":"); i >= 0 {
+			name, typ = spec[:i], strings.TrimSpace(spec[i+1:])
+		}
+		key := name
+		if i := strings.Index(name, "="); i >= 0 {
+			key, name = name[:i], name[i+1:]
+		}
+		key, name = strings.TrimSpace(key), strings.TrimSpace(name)
+		if key == "" || name == "" {
+			return nil, fmt.Errorf("пустое имя поля в %q", spec)
+		}
+		switch typ {
+		case fieldString, fieldNumber, fieldDate, fieldList:
+		default:
+			return nil, fmt.Errorf("неизвестный тип %q поля %s: допускаются string, number, date и list", typ, name)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fields = append(fields, itemField{Key: key, Name: name, Type: typ})
+	}
+	return fields, nil
+}
+
+// item возвращает элемент списка для страницы. Отсутствующие значения записываются как null,
+// а значения, которые не удалось привести к типу поля, — как null с ошибкой.
+func (t *taxonomy) item(model *Model) (map[string]interface{}, []error) {
+	item := make(map[string]interface{}, len(t.Fields))
+	var errs []error
+	for _, field := range t.Fields {
+		if compute, ok := computedFields[field.Name]; ok {
+			item[field.Key] = compute(model)
+			continue
+		}
+		// Даты публикации уже разобраны и выводятся в JSON в RFC 3339
+		if t, ok := model.Dates[field.Name]; ok && (field.Type == fieldString || field.Type == fieldDate) {
+			item[field.Key] = t.Format(time.RFC3339)
+			continue
+		}
+		value, err := fieldValue(model.Data, field)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("поле %s: %v", field.Key, err))
+		}
+		item[field.Key] = value
+	}
+	return item, errs
+}
+
+// fieldValue приводит атрибут страницы к типу поля
+func fieldValue(data map[string]string, field itemField) (interface{}, error) {
+	raw, _ := lookupFlat(data, field.Name)
+	if field.Type == fieldList {
+		items := listItems(raw)
+		if len(items) == 0 {
+			return nil, nil
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = strings.TrimSpace(formatValue(item))
+		}
+		return list, nil
+	}
+
+	value := strings.TrimSpace(formatValue(raw))
+	if value == "" {
+		return nil, nil
+	}
+	switch field.Type {
+	case fieldNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q не является числом", value)
+		}
+		return n, nil
+	case fieldDate:
+		t, ok := parseDate(value)
+		if !ok {
+			return nil, fmt.Errorf("не удалось распознать дату %q", value)
+		}
+		return t.Format(time.RFC3339), nil
+	}
+	return value, nil
+}
+
+// wordCount считает слова в HTML без тегов
+func wordCount(content string) int {
+	return len(strings.Fields(html.UnescapeString(mdHTMLTagStrip.ReplaceAllString(content, " "))))
+}
+
+// Виды значений ключа сортировки в порядке следования
+const (
+	sortNumber = iota + 1
+	sortDate
+	sortString
+)
+
+// sortValue — значение ключа сортировки страницы, разобранное один раз до сортировки
+type sortValue struct {
+	kind int // 0 — значения нет
+	num  float64
+	str  string
+}
+
+// pageSortValue возвращает значение ключа key страницы: идентификатор для id, иначе вычисляемое поле
+// или атрибут, который сравнивается как число, как дата (форматы фильтра date) или как строка без учёта регистра
+func pageSortValue(model *Model, key string) sortValue {
+	if key == "id" {
+		return sortValue{kind: sortString, str: model.ID}
+	}
+	if t, ok := model.Dates[key]; ok {
+		return sortValue{kind: sortDate, num: float64(t.UnixNano())}
+	}
+	value := strings.TrimSpace(model.Data[key])
+	if compute, ok := computedFields[key]; ok {
+		value = formatValue(compute(model))
+	}
+	if value == "" {
+		return sortValue{}
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return sortValue{kind: sortNumber, num: n}
+	}
+	if t, ok := parseDate(value); ok {
+		return sortValue{kind: sortDate, num: float64(t.UnixNano())}
+	}
+	return sortValue{kind: sortString, str: strings.ToLower(value)}
+}
+
+// compareSortValues сравнивает значения одного ключа; значения разных видов упорядочены по виду
+func compareSortValues(a, b sortValue) int {
+	switch {
+	case a.kind != b.kind:
+		return a.kind - b.kind
+	case a.kind == sortString:
+		return strings.Compare(a.str, b.str)
+	case a.num < b.num:
+		return -1
+	case a.num > b.num:
+		return 1
+	}
+	return 0
+}
+
+// sortModels возвращает страницы, упорядоченные по ключу key. Страницы без значения ключа
+// идут в конце при любом направлении, а при равных значениях порядок определяет идентификатор.
+func sortModels(models []*Model, key string, desc bool) []*Model {
+	type keyed struct {
+		model *Model
+		value sortValue
+	}
+	list := make([]keyed, len(models))
+	for i, model := range models {
+		list[i] = keyed{model: model, value: pageSortValue(model, key)}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if (a.value.kind == 0) != (b.value.kind == 0) {
+			return b.value.kind == 0
+		}
+		c := compareSortValues(a.value, b.value)
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return a.model.ID < b.model.ID
+	})
+	sorted := make([]*Model, len(list))
+	for i, k := range list {
+		sorted[i] = k.model
+	}
+	return sorted
+}
+
+// termBase возвращает путь страниц термина. Имена категорий используются как есть, если они
+// не выходят за пределы директории сборки, термины остальных таксономий превращаются в slug;
+// пустая строка — термин без адреса.
+func (t *taxonomy) termBase(term string) (string, error) {
+	if t.legacy {
+		if err := validatePageID(term); err != nil {
+			return "", fmt.Errorf("недопустимое имя категории %q: %v", term, err)
+		}
+		return filepath.ToSlash(term), nil
+	}
+	slug := slugify(term)
+	if slug == "" {
+		return "", nil
+	}
+	return path.Join(t.Name, slug), nil
+}
+
+// termPagePath возвращает путь файла относительно директории сборки и адрес страницы number
+// списка термина. Первая страница — <base>.html (при prettyURLs — <base>/index.html),
+// остальные — <base>/page/<номер>/index.html.
+func termPagePath(base string, number int, prettyURLs bool) (string, string) {
+	switch {
+	case number > 1:
+		dir := path.Join(base, "page", strconv.Itoa(number))
+		return path.Join(dir, "index.html"), "/" + dir + "/"
+	case prettyURLs:
+		return path.Join(base, "index.html"), "/" + base + "/"
+	default:
+		return base + ".html", "/" + base + ".html"
+	}
+}
+
+// loadCollectionTemplate компилирует первый существующий шаблон из names в директории collections.
+// Возвращает nil без ошибки, если ни одного шаблона нет, а также исходник для отпечатка в манифесте.
+func loadCollectionTemplate(opts *Options, names ...string) (*Template, string, error) {
+	for _, name := range names {
+		htmlBytes, err := ioutil.ReadFile(filepath.Join(opts.CollectionsDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("ошибка при чтении шаблона %s: %v", name, err)
+		}
+		// Legacy {{CATEGORY}} placeholder goes through the template engine so it is escaped like {category}
+		source := strings.ReplaceAll(string(htmlBytes), "{{CATEGORY}}", "{category}")
+
+		// Blocks and site variables are available to collection templates, including {if}/{each}
+		tpl, err := compileTemplate(strings.TrimSuffix(name, ".tpl"), source)
+		if err != nil {
+			return nil, "", err
+		}
+		return tpl, source, nil
+	}
+	return nil, "", nil
+}
+
+// collectionData возвращает данные шаблона коллекции: блоки, переменные сайта и имя таксономии
+func collectionData(blocks *BlockSet, tax *taxonomy, size int) map[string]string {
+	data := make(map[string]string, len(blocks.Data)+size+8)
+	for k, v := range blocks.Data {
+		data[k] = v
+	}
+	data["taxonomy"] = tax.Name
+	return data
+}
+
+// writeCollectionFile записывает файл коллекции name в директорию сборки
+func writeCollectionFile(opts *Options, name string, content []byte) error {
+	filePath := filepath.Join(opts.OutputDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("ошибка при записи файла %s: %v", name, err)
+	}
+	if err := ioutil.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("ошибка при записи файла %s: %v", name, err)
+	}
+	return nil
+}
+
+// termPageCount возвращает число страниц списка термина; у термина без элементов одна страница
+func termPageCount(term *taxonomyTerm, pageSize int) int {
+	if total := (len(term.Items) + pageSize - 1) / pageSize; total > 0 {
+		return total
+	}
+	return 1
+}
+
+// outputs возвращает пути файлов, которые запишет задача, не выполняя её
+func (task TermTask) outputs(opts *Options) []string {
+	var outputs []string
+	switch {
+	case task.Taxonomy == nil:
+		// Лента всего сайта
+	case task.Term != nil:
+		if opts.Config.Build.CategoryJSON {
+			outputs = append(outputs, task.Term.Base+".json")
+		}
+		for number := 1; number <= termPageCount(task.Term, opts.Config.Build.PageSize); number++ {
+			htmlName, _ := termPagePath(task.Term.Base, number, opts.Config.Build.PrettyURLs)
+			outputs = append(outputs, htmlName)
+		}
+	default:
+		outputs = append(outputs, path.Join(task.Taxonomy.Name, "index.json"))
+		if task.Template != nil {
+			outputs = append(outputs, path.Join(task.Taxonomy.Name, "index.html"))
+		}
+	}
+	if task.Feed != nil {
+		outputs = append(outputs, feedOutputs(opts, task.Feed)...)
+	}
+	return outputs
+}
+
+// claimOutputs закрепляет пути результата задачи за ней в owners. Если путь уже занят страницей,
+// файлом или другим термином, задача не выполняется, а конфликт возвращается как ошибка.
+func (task TermTask) claimOutputs(opts *Options, owners map[string]string) error {
+	outputs := task.outputs(opts)
+	for _, output := range outputs {
+		if owner, taken := owners[output]; taken {
+			return fmt.Errorf("путь %s уже занят: %s", output, owner)
+		}
+	}
+	for _, output := range outputs {
+		owners[output] = "термин " + task.Key
+	}
+	return nil
+}
+
+// processTerm генерирует страницы одного термина, его ленты и, если включено build.categoryJSON, его JSON.
+// Элементы делятся на страницы по build.pageSize. Возвращает пути всех записанных файлов.
+func processTerm(task TermTask, blocks *BlockSet, opts *Options) ([]string, error) {
+	var outputs []string
+	term := task.Term
+
+	// JSON со всеми элементами нужен только шаблонам, которые загружают его из браузера
+	if opts.Config.Build.CategoryJSON {
+		jsonData, err := json.MarshalIndent(term.Items, "", "  ")
+		if err != nil {
+			return outputs, fmt.Errorf("ошибка при маршалинге JSON для %s: %v", term.Name, err)
+		}
+		jsonName := term.Base + ".json"
+		if err := writeCollectionFile(opts, jsonName, jsonData); err != nil {
+			return outputs, err
+		}
+		outputs = append(outputs, jsonName)
+	}
+
+	pageSize := opts.Config.Build.PageSize
+	total := termPageCount(term, pageSize)
+	pageURL := func(number int) string {
+		_, url := termPagePath(term.Base, number, opts.Config.Build.PrettyURLs)
+		return url
+	}
+
+	for number := 1; number <= total; number++ {
+		start := (number - 1) * pageSize
+		end := start + pageSize
+		if end > len(term.Items) {
+			end = len(term.Items)
+		}
+
+		data := collectionData(blocks, task.Taxonomy, 2*(end-start))
+		data["term"] = term.Name
+		if task.Taxonomy.legacy {
+			data["category"] = term.Name
+		}
+		for i, item := range term.Items[start:end] {
+			for field, value := range item {
+				flattenConfigValue("items."+strconv.Itoa(i)+"."+field, value, data)
+			}
+		}
+		data["page.number"] = strconv.Itoa(number)
+		data["page.total"] = strconv.Itoa(total)
+		data["page.first"] = pageURL(1)
+		data["page.last"] = pageURL(total)
+		data["page.prev"], data["page.next"] = "", ""
+		if number > 1 {
+			data["page.prev"] = pageURL(number - 1)
+		}
+		if number < total {
+			data["page.next"] = pageURL(number + 1)
+		}
+
+		safe := make(map[string]bool)
+		if err := blocks.expand(data, safe); err != nil {
+			return outputs, err
+		}
+		htmlContent, err := task.Template.ExecuteBytes(data, safe, blocks.assets)
+		if err != nil {
+			return outputs, err
+		}
+		if opts.minifies(task.Template.Name) {
+			htmlContent = minifyHTML(htmlContent)
+		}
+
+		htmlName, _ := termPagePath(term.Base, number, opts.Config.Build.PrettyURLs)
+		if err := writeCollectionFile(opts, htmlName, htmlContent); err != nil {
+			return outputs, err
+		}
+		outputs = append(outputs, htmlName)
+	}
+
+	if task.Feed != nil {
+		feedOutputs, err := writeFeeds(opts, task.Feed)
+		outputs = append(outputs, feedOutputs...)
+		if err != nil {
+			return outputs, err
+		}
+	}
+	return outputs, nil
+}
+
+// termSummary — запись указателя терминов
+type termSummary struct {
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Count int    `json:"count"` // Число страниц с термином
+}
+
+// termSummaries возвращает имя, адрес и число страниц каждого термина для указателя и его JSON
+func termSummaries(terms []*taxonomyTerm, prettyURLs bool) []termSummary {
+	summaries := make([]termSummary, len(terms))
+	for i, term := range terms {
+		_, url := termPagePath(term.Base, 1, prettyURLs)
+		summaries[i] = termSummary{Name: term.Name, URL: url, Count: len(term.Items)}
+	}
+	return summaries
+}
+
+// processTermIndex генерирует указатель терминов таксономии: <таксономия>/index.json
+// и, если есть шаблон, <таксономия>/index.html. Возвращает пути записанных файлов.
+func processTermIndex(task TermTask, blocks *BlockSet, opts *Options) ([]string, error) {
+	var outputs []string
+	summaries := termSummaries(task.Terms, opts.Config.Build.PrettyURLs)
+
+	jsonData, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return outputs, fmt.Errorf("ошибка при маршалинге JSON для %s: %v", task.Taxonomy.Name, err)
+	}
+	jsonName := path.Join(task.Taxonomy.Name, "index.json")
+	if err := writeCollectionFile(opts, jsonName, jsonData); err != nil {
+		return outputs, err
+	}
+	outputs = append(outputs, jsonName)
+
+	if task.Template == nil {
+		return outputs, nil
+	}
+	data := collectionData(blocks, task.Taxonomy, 3*len(summaries))
+	for i, summary := range summaries {
+		prefix := "terms." + strconv.Itoa(i) + "."
+		data[prefix+"name"] = summary.Name
+		data[prefix+"url"] = summary.URL
+		data[prefix+"count"] = strconv.Itoa(summary.Count)
+	}
+	safe := make(map[string]bool)
+	if err := blocks.expand(data, safe); err != nil {
+		return outputs, err
+	}
+	htmlContent, err := task.Template.ExecuteBytes(data, safe, blocks.assets)
+	if err != nil {
+		return outputs, err
+	}
+	if opts.minifies(task.Template.Name) {
+		htmlContent = minifyHTML(htmlContent)
+	}
+	htmlName := path.Join(task.Taxonomy.Name, "index.html")
+	if err := writeCollectionFile(opts, htmlName, htmlContent); err != nil {
+		return outputs, err
+	}
+	return append(outputs, htmlName), nil
+}
+
+// taxonomyTasks готовит задачи генерации одной таксономии. Термины и указатель, элементы и шаблон
+// которых совпадают с предыдущей сборкой (prev), сразу переносятся в next и возвращаются с Kept.
+// Задачи, пути которых уже заняты в owners, не выполняются и попадают в отчёт как ошибки.
+func taxonomyTasks(tax *taxonomy, models []*Model, blocks *BlockSet, opts *Options, report *BuildReport, prev, next *BuildManifest, owners map[string]string) ([]TermTask, []*taxonomyTerm, error) {
+	terms := tax.collect(models, func(id string, err error) {
+		report.addWarning(stageCategory, id, fmt.Sprintf("%s: %v", tax.Name, err))
+	}, func(id string, err error) {
+		report.addError(stageCategory, id, err)
+	})
+	if len(terms) == 0 {
+		return nil, nil, nil
+	}
+
+	// Для категорий сохраняется прежнее имя шаблона category.tpl
+	termTpl, termSource, err := loadCollectionTemplate(opts, tax.Name+".tpl", "taxonomy.tpl")
+	if err != nil {
+		return nil, terms, err
+	}
+	if termTpl == nil {
+		return nil, terms, fmt.Errorf("не найден шаблон %s.tpl или taxonomy.tpl в %s", tax.Name, opts.CollectionsDir)
+	}
+	indexTpl, indexSource, err := loadCollectionTemplate(opts, tax.Name+".terms.tpl", "terms.tpl")
+	if err != nil {
+		return nil, terms, err
+	}
+
+	var tasks []TermTask
+	add := func(task TermTask) {
+		if err := task.claimOutputs(opts, owners); err != nil {
+			report.addError(stageCategory, task.Key, err)
+			return
+		}
+		if old := prev.Categories[task.Key]; old != nil && old.Fingerprint == task.Fingerprint && outputsExist(opts.OutputDir, old.Outputs) {
+			next.setCategory(task.Key, old)
+			task.Kept = true
+		}
+		tasks = append(tasks, task)
+	}
+
+	shared := contentHash(termSource) + blocks.hash() + blocks.assets.digest(termTpl, blocks)
+	for _, term := range terms {
+		itemsJSON, _ := json.Marshal(term.Items)
+		task := TermTask{
+			Taxonomy: tax,
+			Term:     term,
+			Template: termTpl,
+			Key:      tax.Name + "/" + term.Name,
+		}
+		// Лента лежит рядом со страницами термина: tags/go/rss.xml, а для категорий — main/rss.xml
+		var feedJSON []byte
+		if feedsEnabled(opts.Config) {
+			_, url := termPagePath(term.Base, 1, opts.Config.Build.PrettyURLs)
+			title := siteString(opts.Config, "title", "goferret") + ": " + term.Name
+			task.Feed = newSiteFeed(title, absoluteURL(siteBaseURL(opts.Config), url), term.Base, term.Pages, opts.Config)
+			feedJSON, _ = json.Marshal(task.Feed)
+		}
+		task.Fingerprint = contentHash(shared + term.Name + "\x00" + string(itemsJSON) + string(feedJSON))
+		add(task)
+	}
+	summaryJSON, _ := json.Marshal(termSummaries(terms, opts.Config.Build.PrettyURLs))
+	indexAssets := ""
+	if indexTpl != nil {
+		indexAssets = blocks.assets.digest(indexTpl, blocks)
+	}
+	add(TermTask{
+		Taxonomy:    tax,
+		Terms:       terms,
+		Template:    indexTpl,
+		Key:         tax.Name + "/",
+		Fingerprint: contentHash(contentHash(indexSource) + blocks.hash() + indexAssets + string(summaryJSON)),
+	})
+	return tasks, terms, nil
+}
+
+// taxonomyPlan готовит задачи всех таксономий и ленты сайта и возвращает их вместе с числом терминов.
+// Ошибки шаблонов и конфликты путей с owners попадают в отчёт report.
+func taxonomyPlan(models []*Model, blocks *BlockSet, opts *Options, report *BuildReport, prev, next *BuildManifest, owners map[string]string) ([]TermTask, int) {
+	var tasks []TermTask
+	termCount := 0
+	for _, tax := range siteTaxonomies(opts.Config) {
+		taxTasks, terms, err := taxonomyTasks(tax, models, blocks, opts, report, prev, next, owners)
+		termCount += len(terms)
+		if err != nil {
+			report.addError(stageCategory, tax.Name, err)
+			// Файлы таксономии сохраняются до следующей успешной сборки
+			for key, old := range prev.Categories {
+				if strings.HasPrefix(key, tax.Name+"/") {
+					next.setCategory(key, &ManifestCategory{Outputs: old.Outputs})
+				}
+			}
+			continue
+		}
+		tasks = append(tasks, taxTasks...)
+	}
+	if task := siteFeedTask(models, opts, report, prev, next, owners); task != nil {
+		tasks = append(tasks, *task)
+	}
+	return tasks, termCount
+}
+
+// generateTaxonomies генерирует страницы всех терминов, указатели всех таксономий и ленту сайта
+// в пуле воркеров. Пути, занятые страницами и файлами (owners), не перезаписываются.
+// Ошибки попадают в отчёт report. Возвращает задачи, файлы которых есть в сборке, в том числе
+// не изменившиеся с предыдущей сборки.
+func generateTaxonomies(models []*Model, blocks *BlockSet, opts *Options, report *BuildReport, prev, next *BuildManifest, owners map[string]string) []TermTask {
+	// Create build directory if not exists
+	if _, err := os.Stat(opts.OutputDir); os.IsNotExist(err) {
+		os.MkdirAll(opts.OutputDir, 0755)
+	}
+
+	tasks, termCount := taxonomyPlan(models, blocks, opts, report, prev, next, owners)
+
+	chTasks := make(chan TermTask, len(tasks))
+	var wg sync.WaitGroup
+
+	numWorkers := opts.Config.Build.CategoryWorkers
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range chTasks {
+				var outputs []string
+				var err error
+				switch {
+				case task.Taxonomy == nil:
+					outputs, err = writeFeeds(opts, task.Feed)
+				case task.Term != nil:
+					outputs, err = processTerm(task, blocks, opts)
+				default:
+					outputs, err = processTermIndex(task, blocks, opts)
+				}
+				if err != nil {
+					report.addError(stageCategory, task.Key, err)
+					// Без отпечатка термин соберётся заново в следующий раз, а его файлы не удаляются
+					if old := prev.Categories[task.Key]; old != nil {
+						outputs = append(outputs, old.Outputs...)
+					}
+					next.setCategory(task.Key, &ManifestCategory{Outputs: outputs})
+					continue
+				}
+				next.setCategory(task.Key, &ManifestCategory{Fingerprint: task.Fingerprint, Outputs: outputs})
+			}
+		}()
+	}
+	for _, task := range tasks {
+		if !task.Kept {
+			chTasks <- task
+		}
+	}
+	close(chTasks)
+
+	wg.Wait()
+	report.Categories = termCount
+	return tasks
+}
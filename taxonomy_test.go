@@ -0,0 +1,331 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты таксономий: пути результата, постраничный вывод, сортировка и поля элементов.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTermTaskClaimOutputs(t *testing.T) {
+	opts := &Options{Config: defaultConfig()}
+	category := &taxonomy{Name: categoryTaxonomy, legacy: true}
+	tags := &taxonomy{Name: "tags"}
+	owners := map[string]string{"contact.html": "страница contact"}
+
+	tests := []struct {
+		name string
+		task TermTask
+		want string // Фрагмент ошибки; пустой — пути свободны
+	}{
+		{"категория на месте страницы", TermTask{Taxonomy: category, Term: &taxonomyTerm{Name: "contact", Base: "contact"}, Key: "category/contact"},
+			"путь contact.html уже занят: страница contact"},
+		{"свободная категория", TermTask{Taxonomy: category, Term: &taxonomyTerm{Name: "main", Base: "main"}, Key: "category/main"}, ""},
+		{"термин на месте другого термина", TermTask{Taxonomy: category, Term: &taxonomyTerm{Name: "tags/go", Base: "tags/go"}, Key: "category/tags/go"}, ""},
+		{"конфликт терминов", TermTask{Taxonomy: tags, Term: &taxonomyTerm{Name: "Go", Base: "tags/go"}, Key: "tags/Go"},
+			"путь tags/go.json уже занят: термин category/tags/go"},
+		{"указатель", TermTask{Taxonomy: tags, Key: "tags/"}, ""},
+	}
+	for _, tt := range tests {
+		err := tt.task.claimOutputs(opts, owners)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: ошибка %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.want)
+		}
+	}
+	if owners["main.html"] != "термин category/main" || owners["tags/index.json"] != "термин tags/" {
+		t.Errorf("пути не закреплены: %v", owners)
+	}
+}
+
+func TestTermPagePath(t *testing.T) {
+	tests := []struct {
+		base   string
+		number int
+		pretty bool
+		path   string
+		url    string
+	}{
+		{"main", 1, false, "main.html", "/main.html"},
+		{"main", 1, true, "main/index.html", "/main/"},
+		{"main", 2, false, "main/page/2/index.html", "/main/page/2/"},
+		{"main", 2, true, "main/page/2/index.html", "/main/page/2/"},
+		{"tags/go", 1, false, "tags/go.html", "/tags/go.html"},
+		{"tags/go", 1, true, "tags/go/index.html", "/tags/go/"},
+		{"tags/go", 12, false, "tags/go/page/12/index.html", "/tags/go/page/12/"},
+	}
+	for _, tt := range tests {
+		path, url := termPagePath(tt.base, tt.number, tt.pretty)
+		if path != tt.path || url != tt.url {
+			t.Errorf("termPagePath(%q, %d, %v) = %q, %q; ожидалось %q, %q", tt.base, tt.number, tt.pretty, path, url, tt.path, tt.url)
+		}
+	}
+}
+
+func TestProcessTermPagination(t *testing.T) {
+	blocks, err := getBlocksSubModel(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := compileTemplate("taxonomy",
+		"{page.number}/{page.total} {page.first} {page.last} prev=[{page.prev}] next=[{page.next}] {each items}{title};{end}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	term := &taxonomyTerm{Name: "go", Base: "tags/go"}
+	for i := 1; i <= 5; i++ {
+		term.Items = append(term.Items, map[string]interface{}{"title": fmt.Sprintf("T%d", i)})
+	}
+	task := TermTask{Taxonomy: &taxonomy{Name: "tags"}, Term: term, Template: tpl, Key: "tags/go"}
+
+	tests := []struct {
+		name         string
+		pageSize     int
+		pretty       bool
+		categoryJSON bool
+		pages        map[string]string // Файл страницы списка -> ожидаемое содержимое
+	}{
+		{"три страницы", 2, false, true, map[string]string{
+			"tags/go.html":              "1/3 /tags/go.html /tags/go/page/3/ prev=[] next=[/tags/go/page/2/] T1;T2;",
+			"tags/go/page/2/index.html": "2/3 /tags/go.html /tags/go/page/3/ prev=[/tags/go.html] next=[/tags/go/page/3/] T3;T4;",
+			"tags/go/page/3/index.html": "3/3 /tags/go.html /tags/go/page/3/ prev=[/tags/go/page/2/] next=[] T5;",
+		}},
+		{"чистые адреса", 2, true, false, map[string]string{
+			"tags/go/index.html":        "1/3 /tags/go/ /tags/go/page/3/ prev=[] next=[/tags/go/page/2/] T1;T2;",
+			"tags/go/page/2/index.html": "2/3 /tags/go/ /tags/go/page/3/ prev=[/tags/go/] next=[/tags/go/page/3/] T3;T4;",
+			"tags/go/page/3/index.html": "3/3 /tags/go/ /tags/go/page/3/ prev=[/tags/go/page/2/] next=[] T5;",
+		}},
+		{"все элементы на одной странице", 5, false, false, map[string]string{
+			"tags/go.html": "1/1 /tags/go.html /tags/go.html prev=[] next=[] T1;T2;T3;T4;T5;",
+		}},
+	}
+	for _, tt := range tests {
+		opts := &Options{OutputDir: t.TempDir(), Config: defaultConfig()}
+		opts.Config.Build.PageSize = tt.pageSize
+		opts.Config.Build.PrettyURLs = tt.pretty
+		opts.Config.Build.CategoryJSON = tt.categoryJSON
+
+		outputs, err := processTerm(task, blocks, opts)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		var want []string
+		for name, content := range tt.pages {
+			want = append(want, name)
+			data, err := os.ReadFile(filepath.Join(opts.OutputDir, filepath.FromSlash(name)))
+			if err != nil || string(data) != content {
+				t.Errorf("%s: %s = %q, ошибка %v; ожидалось %q", tt.name, name, data, err, content)
+			}
+		}
+		if tt.categoryJSON {
+			want = append(want, "tags/go.json")
+		}
+		sort.Strings(want)
+		sort.Strings(outputs)
+		if !reflect.DeepEqual(outputs, want) {
+			t.Errorf("%s: записаны %v, ожидались %v", tt.name, outputs, want)
+		}
+
+		// JSON термина содержит все элементы, а не только первую страницу
+		data, err := os.ReadFile(filepath.Join(opts.OutputDir, "tags", "go.json"))
+		switch {
+		case !tt.categoryJSON && !os.IsNotExist(err):
+			t.Errorf("%s: JSON записан при categoryJSON = false: %v", tt.name, err)
+		case tt.categoryJSON:
+			var items []map[string]interface{}
+			if err := json.Unmarshal(data, &items); err != nil || len(items) != len(term.Items) {
+				t.Errorf("%s: JSON %s, ошибка %v", tt.name, data, err)
+			}
+		}
+	}
+}
+
+func TestTermTaskClaimFeedOutputs(t *testing.T) {
+	opts := &Options{Config: defaultConfig()}
+	opts.Config.Feeds.Formats = []string{feedRSS, feedAtom}
+	category := &taxonomy{Name: categoryTaxonomy, legacy: true}
+	owners := map[string]string{"main/atom.xml": "static/main/atom.xml", "rss.xml": "static/rss.xml"}
+
+	term := TermTask{Taxonomy: category, Term: &taxonomyTerm{Name: "main", Base: "main"}, Key: "category/main", Feed: &siteFeed{Dir: "main"}}
+	if err := term.claimOutputs(opts, owners); err == nil || !strings.Contains(err.Error(), "путь main/atom.xml уже занят: static/main/atom.xml") {
+		t.Errorf("лента термина: ошибка %v", err)
+	}
+	if _, taken := owners["main.html"]; taken {
+		t.Errorf("пути термина с конфликтом закреплены: %v", owners)
+	}
+	site := TermTask{Key: siteFeedKey, Feed: &siteFeed{}}
+	if err := site.claimOutputs(opts, owners); err == nil || !strings.Contains(err.Error(), "путь rss.xml уже занят") {
+		t.Errorf("лента сайта: ошибка %v", err)
+	}
+}
+
+func TestCollectRejectsUnsafeCategories(t *testing.T) {
+	category := siteTaxonomies(defaultConfig())[0]
+	models := []*Model{
+		{ID: "a", Category: "main"},
+		{ID: "b", Category: "../x"},
+		{ID: "c", Category: "/etc/x"},
+		{ID: "d", Category: "news/2024"},
+	}
+	var failed []string
+	terms := category.collect(models, func(id string, err error) {
+		t.Errorf("%s: предупреждение %v", id, err)
+	}, func(id string, err error) {
+		failed = append(failed, id)
+	})
+	var bases []string
+	for _, term := range terms {
+		bases = append(bases, term.Base)
+	}
+	if got := strings.Join(bases, ","); got != "main,news/2024" {
+		t.Errorf("термины %s, ожидались main,news/2024", got)
+	}
+	if got := strings.Join(failed, ","); got != "b,c" {
+		t.Errorf("ошибки у страниц %s, ожидались b,c", got)
+	}
+}
+
+func TestSortModels(t *testing.T) {
+	page := func(id string, data map[string]string) *Model {
+		return &Model{ID: id, Data: data}
+	}
+	models := []*Model{
+		page("e", map[string]string{"weight": "10", "title": "beta", "date": "2025-07-03"}),
+		page("b", map[string]string{"weight": "2", "title": "Alpha", "date": "2025-07-01"}),
+		page("d", map[string]string{"title": "gamma"}),
+		page("a", map[string]string{"weight": "2", "title": "alpha", "date": "2025-07-02"}),
+		page("c", map[string]string{"weight": "", "content": "<p>one two three</p>"}),
+	}
+	tests := []struct {
+		key  string
+		desc bool
+		want string
+	}{
+		{"id", false, "a,b,c,d,e"},
+		{"id", true, "e,d,c,b,a"},
+		// Числа сравниваются как числа, при равенстве порядок задаёт идентификатор, пустые значения — в конце
+		{"weight", false, "a,b,e,c,d"},
+		{"weight", true, "e,a,b,c,d"},
+		// Строки сравниваются без учёта регистра
+		{"title", false, "a,b,e,d,c"},
+		{"date", true, "e,a,b,c,d"},
+		{"wordCount", false, "a,b,d,e,c"},
+		{"missing", false, "a,b,c,d,e"},
+	}
+	for _, tt := range tests {
+		var ids []string
+		for _, model := range sortModels(models, tt.key, tt.desc) {
+			ids = append(ids, model.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%s desc=%v: получено %s, ожидалось %s", tt.key, tt.desc, got, tt.want)
+		}
+	}
+	if models[0].ID != "e" {
+		t.Error("sortModels изменил исходный срез")
+	}
+}
+
+func TestParseItemFields(t *testing.T) {
+	tests := []struct {
+		specs []string
+		want  []itemField
+		err   string
+	}{
+		{nil, defaultItemFields, ""},
+		{[]string{"summary", "date:date", "price:number", "tags:list", "author=author.name", " words = wordCount : number "},
+			append(append([]itemField(nil), defaultItemFields...),
+				itemField{Key: "summary", Name: "summary", Type: fieldString},
+				itemField{Key: "date", Name: "date", Type: fieldDate},
+				itemField{Key: "price", Name: "price", Type: fieldNumber},
+				itemField{Key: "tags", Name: "tags", Type: fieldList},
+				itemField{Key: "author", Name: "author.name", Type: fieldString},
+				itemField{Key: "words", Name: "wordCount", Type: fieldNumber},
+			), ""},
+		{[]string{"title", "summary", "summary:number"},
+			append(append([]itemField(nil), defaultItemFields...), itemField{Key: "summary", Name: "summary", Type: fieldString}), ""},
+		{[]string{"price:money"}, nil, `неизвестный тип "money" поля price`},
+		{[]string{":date"}, nil, "пустое имя поля"},
+		{[]string{"x="}, nil, "пустое имя поля"},
+	}
+	for _, tt := range tests {
+		got, err := parseItemFields(tt.specs)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: ошибка %v, ожидалась содержащая %q", tt.specs, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("%q: ошибка %v", tt.specs, err)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("%q: получено %+v, ожидалось %+v", tt.specs, got, tt.want)
+		}
+	}
+}
+
+func TestTaxonomyItem(t *testing.T) {
+	fields, err := parseItemFields([]string{"id", "summary", "date:date", "price:number", "tags:list",
+		"author=author.name", "wordCount", "readingTime", "bad:number", "when:date", "missing:number"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tax := &taxonomy{Name: "tags", Fields: fields}
+	model := &Model{
+		ID:  "docs/a",
+		URL: "/docs/a.html",
+		Data: map[string]string{
+			"title":       "A",
+			"summary":     " Кратко ",
+			"price":       "9.5",
+			"tags.0":      "go",
+			"tags.1":      "site",
+			"author.name": "Автор",
+			"bad":         "много",
+			"when":        "завтра",
+			"content":     "<p>" + strings.Repeat("слово ", 401) + "&amp; <b>x</b></p>",
+		},
+		Dates: map[string]time.Time{"date": time.Date(2025, 7, 2, 10, 0, 0, 0, time.UTC)},
+	}
+	item, errs := tax.item(model)
+	want := map[string]interface{}{
+		"title":       "A",
+		"url":         "/docs/a.html",
+		"id":          "docs/a",
+		"summary":     "Кратко",
+		"date":        "2025-07-02T10:00:00Z",
+		"price":       9.5,
+		"tags":        []interface{}{"go", "site"},
+		"author":      "Автор",
+		"wordCount":   403,
+		"readingTime": 3,
+		"bad":         nil,
+		"when":        nil,
+		"missing":     nil,
+	}
+	if !reflect.DeepEqual(item, want) {
+		t.Errorf("получено %#v\nожидалось %#v", item, want)
+	}
+	var causes []string
+	for _, err := range errs {
+		causes = append(causes, err.Error())
+	}
+	wantErrs := `поле bad: "много" не является числом; поле when: не удалось распознать дату "завтра"`
+	if got := strings.Join(causes, "; "); got != wantErrs {
+		t.Errorf("ошибки %q, ожидались %q", got, wantErrs)
+	}
+}
@@ -0,0 +1,1061 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Язык шаблонов goferret: подстановки, условия, циклы, смена контекста и наследование.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Template — разобранный шаблон, готовый к многократному рендерингу
+type Template struct {
+	Name     string
+	Vars     map[string]string // Переменные шаблона, которые регистрируются в модели страницы
+	nodes    []tplNode
+	extends  string               // Имя базового шаблона из {extends}
+	sections map[string][]tplNode // Секции, объявленные в этом шаблоне
+	parent   *Template            // Разрешённый базовый шаблон
+	refs     []string             // Пути всех значений, на которые ссылается шаблон
+	assets   []string             // Ссылки {asset} и {integrity} в виде "asset:<путь>" и "integrity:<путь>"
+	hash     string               // Отпечаток исходного текста для манифеста сборки
+}
+
+// tplNode — узел дерева шаблона
+type tplNode interface{}
+
+// textNode — неизменяемый фрагмент текста шаблона
+type textNode struct {
+	text string
+	last atomic.Value // Последний переход контекста экранирования (escTransition)
+}
+
+// escTransition — контекст до и после вывода текстового узла
+type escTransition struct {
+	in, out escContext
+}
+
+// advance возвращает контекст после вывода узла. Узел почти всегда выводится из одного и того же
+// контекста, поэтому последний переход запоминается и текст не разбирается для каждой страницы.
+func (n *textNode) advance(c escContext) escContext {
+	if t, ok := n.last.Load().(escTransition); ok && t.in == c {
+		return t.out
+	}
+	out := c.advance(n.text)
+	n.last.Store(escTransition{in: c, out: out})
+	return out
+}
+
+// varNode — подстановка {path}
+type varNode struct {
+	path      []string // Пустой путь означает текущий элемент {.}
+	raw       string   // Исходный текст тега для неизвестных переменных
+	filters   []filterCall
+	unescaped bool // Последний фильтр (например, raw) отключает экранирование
+}
+
+// ifNode — условие {if path}...{else}...{end}
+type ifNode struct {
+	path   []string
+	negate bool
+	then   []tplNode
+	els    []tplNode
+}
+
+// eachNode — цикл {each path}...{else}...{end}
+type eachNode struct {
+	path []string
+	body []tplNode
+	els  []tplNode
+}
+
+// withNode — смена контекста {with path}...{else}...{end}
+type withNode struct {
+	path []string
+	body []tplNode
+	els  []tplNode
+}
+
+// sectionNode — переопределяемая секция {section name}...{end}
+type sectionNode struct {
+	name string
+	body []tplNode
+}
+
+// assetNode — адрес файла из static/ {asset "css/app.css"} или его хэш SRI {integrity "css/app.css"}
+type assetNode struct {
+	name      string
+	integrity bool
+	raw       string
+}
+
+// key возвращает ссылку узла на файл в виде, в котором она хранится в Template.assets
+func (n *assetNode) key() string {
+	if n.integrity {
+		return "integrity:" + n.name
+	}
+	return "asset:" + n.name
+}
+
+// Виды тегов шаблона
+const (
+	tagVar = iota
+	tagIf
+	tagEach
+	tagWith
+	tagExtends
+	tagSection
+	tagAsset
+	tagIntegrity
+	tagElse
+	tagEnd
+)
+
+// tplTag — распознанный тег в фигурных скобках
+type tplTag struct {
+	kind    int
+	path    []string
+	name    string // Имя шаблона для {extends} или секции для {section}
+	negate  bool
+	filters []filterCall
+	raw     string
+	line    int
+}
+
+// compileTemplate разбирает текст шаблона name в дерево узлов.
+// Фигурные скобки, содержимое которых не является тегом (CSS, JavaScript), остаются текстом.
+func compileTemplate(name, src string) (*Template, error) {
+	p := &tplParser{name: name, src: src, line: 1}
+	nodes, end, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, p.errorf(end.line, "лишний тег %s", end.raw)
+	}
+
+	tpl := &Template{
+		Name:    name,
+		Vars:    parseTemplateVars(nodes),
+		nodes:   nodes,
+		extends: p.extends,
+		refs:    templateRefs(nodes),
+		assets:  templateAssets(nodes),
+		hash:    contentHash(src),
+	}
+	if tpl.sections, err = collectSections(name, nodes); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// collectSections собирает секции шаблона на любой глубине вложенности
+func collectSections(name string, nodes []tplNode) (map[string][]tplNode, error) {
+	sections := make(map[string][]tplNode)
+	var walk func(nodes []tplNode) error
+	walk = func(nodes []tplNode) error {
+		for _, node := range nodes {
+			var children [][]tplNode
+			switch n := node.(type) {
+			case *sectionNode:
+				if _, exists := sections[n.name]; exists {
+					return fmt.Errorf("шаблон %s: секция %s объявлена дважды", name, n.name)
+				}
+				sections[n.name] = n.body
+				children = [][]tplNode{n.body}
+			case *ifNode:
+				children = [][]tplNode{n.then, n.els}
+			case *eachNode:
+				children = [][]tplNode{n.body, n.els}
+			case *withNode:
+				children = [][]tplNode{n.body, n.els}
+			}
+			for _, child := range children {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(nodes); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// tplParser разбирает текст шаблона, продвигаясь от тега к тегу
+type tplParser struct {
+	name    string
+	src     string
+	pos     int
+	line    int
+	depth   int    // Глубина вложенности блочных тегов
+	extends string // Базовый шаблон из {extends}
+}
+
+func (p *tplParser) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("шаблон %s, строка %d: %s", p.name, line, fmt.Sprintf(format, args...))
+}
+
+// nextTag возвращает текст до следующего тега и сам тег; тег равен nil в конце шаблона
+func (p *tplParser) nextTag() (string, *tplTag, error) {
+	start := p.pos
+	for i := p.pos; i < len(p.src); i++ {
+		if p.src[i] != '{' {
+			continue
+		}
+		end := strings.IndexAny(p.src[i+1:], "{}\n")
+		if end < 0 || p.src[i+1+end] != '}' {
+			continue
+		}
+		raw := p.src[i : i+end+2]
+		tag, ok, err := parseTag(raw[1 : len(raw)-1])
+		if !ok {
+			continue
+		}
+		text := p.src[start:i]
+		p.line += strings.Count(text, "\n")
+		if err != nil {
+			return "", nil, p.errorf(p.line, "%s: %v", raw, err)
+		}
+		tag.raw = raw
+		tag.line = p.line
+		p.pos = i + len(raw)
+		return text, tag, nil
+	}
+	text := p.src[start:]
+	p.line += strings.Count(text, "\n")
+	p.pos = len(p.src)
+	return text, nil, nil
+}
+
+// parseNodes разбирает узлы до тега {else} или {end}, который возвращается вызывающему
+func (p *tplParser) parseNodes() ([]tplNode, *tplTag, error) {
+	var nodes []tplNode
+	for {
+		text, tag, err := p.nextTag()
+		if err != nil {
+			return nil, nil, err
+		}
+		if text != "" {
+			nodes = append(nodes, &textNode{text: text})
+		}
+		if tag == nil {
+			return nodes, nil, nil
+		}
+
+		switch tag.kind {
+		case tagVar:
+			node := &varNode{path: tag.path, raw: tag.raw, filters: tag.filters}
+			if len(tag.filters) > 0 {
+				node.unescaped = templateFilters[tag.filters[len(tag.filters)-1].name].unescaped
+			}
+			nodes = append(nodes, node)
+		case tagAsset, tagIntegrity:
+			nodes = append(nodes, &assetNode{name: tag.name, integrity: tag.kind == tagIntegrity, raw: tag.raw})
+		case tagElse, tagEnd:
+			return nodes, tag, nil
+		case tagExtends:
+			if p.depth > 0 || p.extends != "" {
+				return nil, nil, p.errorf(tag.line, "тег %s допустим только один раз на верхнем уровне шаблона", tag.raw)
+			}
+			p.extends = tag.name
+		case tagSection:
+			p.depth++
+			body, end, err := p.parseNodes()
+			p.depth--
+			if err != nil {
+				return nil, nil, err
+			}
+			if end == nil || end.kind != tagEnd {
+				return nil, nil, p.errorf(tag.line, "тег %s не закрыт тегом {end}", tag.raw)
+			}
+			nodes = append(nodes, &sectionNode{name: tag.name, body: body})
+		default:
+			body, els, err := p.parseBody(tag)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch tag.kind {
+			case tagIf:
+				nodes = append(nodes, &ifNode{path: tag.path, negate: tag.negate, then: body, els: els})
+			case tagEach:
+				nodes = append(nodes, &eachNode{path: tag.path, body: body, els: els})
+			case tagWith:
+				nodes = append(nodes, &withNode{path: tag.path, body: body, els: els})
+			}
+		}
+	}
+}
+
+// parseBody разбирает тело блочного тега и необязательную ветку {else} до {end}
+func (p *tplParser) parseBody(open *tplTag) ([]tplNode, []tplNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	body, end, err := p.parseNodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if end == nil {
+		return nil, nil, p.errorf(open.line, "тег %s не закрыт тегом {end}", open.raw)
+	}
+	if end.kind == tagEnd {
+		return body, nil, nil
+	}
+
+	els, end, err := p.parseNodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if end == nil {
+		return nil, nil, p.errorf(open.line, "тег %s не закрыт тегом {end}", open.raw)
+	}
+	if end.kind == tagElse {
+		return nil, nil, p.errorf(end.line, "повторный {else} в теге %s", open.raw)
+	}
+	return body, els, nil
+}
+
+// parseTag распознаёт содержимое фигурных скобок. Возвращает false, если это не тег шаблона,
+// и ошибку, если тег записан с неизвестным фильтром или неверным числом аргументов.
+func parseTag(content string) (*tplTag, bool, error) {
+	if content == "" || content[0] == ' ' || content[0] == '\t' {
+		return nil, false, nil
+	}
+	switch content {
+	case "else":
+		return &tplTag{kind: tagElse}, true, nil
+	case "end":
+		return &tplTag{kind: tagEnd}, true, nil
+	}
+
+	keyword, arg := content, ""
+	if i := strings.IndexByte(content, ' '); i >= 0 {
+		keyword, arg = content[:i], strings.TrimSpace(content[i+1:])
+	}
+	tag := &tplTag{}
+	switch keyword {
+	case "if":
+		tag.kind = tagIf
+		if strings.HasPrefix(arg, "not ") {
+			tag.negate = true
+			arg = strings.TrimSpace(arg[4:])
+		}
+	case "each":
+		tag.kind = tagEach
+	case "with":
+		tag.kind = tagWith
+	case "extends", "section":
+		tag.kind = tagSection
+		if keyword == "extends" {
+			tag.kind = tagExtends
+		}
+		if !isTemplateName(arg, keyword == "extends") {
+			return nil, false, nil
+		}
+		tag.name = arg
+		return tag, true, nil
+	case "asset", "integrity":
+		tag.kind = tagAsset
+		if keyword == "integrity" {
+			tag.kind = tagIntegrity
+		}
+		name, err := strconv.Unquote(arg)
+		if err != nil || !isAssetName(name) {
+			return nil, false, nil
+		}
+		tag.name = name
+		return tag, true, nil
+	default:
+		tag.kind = tagVar
+		arg = content
+		if i := strings.IndexByte(content, '|'); i >= 0 {
+			filters, ok, err := parseFilters(content[i+1:])
+			if !ok || err != nil {
+				return nil, ok, err
+			}
+			tag.filters = filters
+			arg = strings.TrimSpace(content[:i])
+		}
+	}
+
+	path, ok := parsePath(arg)
+	if !ok {
+		return nil, false, nil
+	}
+	tag.path = path
+	return tag, true, nil
+}
+
+// parsePath разбирает путь к значению: name, site.title, tags.0 или "." для текущего элемента
+func parsePath(s string) ([]string, bool) {
+	if s == "." {
+		return []string{}, true
+	}
+	if s == "" {
+		return nil, false
+	}
+	parts := strings.Split(s, ".")
+	for i, part := range parts {
+		if part == "" {
+			return nil, false
+		}
+		for j := 0; j < len(part); j++ {
+			c := part[j]
+			letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+			digit := c >= '0' && c <= '9'
+			if !(letter || (j > 0 || i > 0) && (digit || c == '-')) {
+				return nil, false
+			}
+		}
+	}
+	return parts, true
+}
+
+// templateRefs возвращает пути всех значений, на которые ссылается шаблон, в порядке появления
+func templateRefs(nodes []tplNode) []string {
+	var refs []string
+	var walk func(nodes []tplNode)
+	walk = func(nodes []tplNode) {
+		for _, node := range nodes {
+			var path []string
+			var children [][]tplNode
+			switch n := node.(type) {
+			case *varNode:
+				path = n.path
+			case *ifNode:
+				path, children = n.path, [][]tplNode{n.then, n.els}
+			case *eachNode:
+				path, children = n.path, [][]tplNode{n.body, n.els}
+			case *withNode:
+				path, children = n.path, [][]tplNode{n.body, n.els}
+			case *sectionNode:
+				children = [][]tplNode{n.body}
+			}
+			if len(path) > 0 {
+				refs = append(refs, strings.Join(path, "."))
+			}
+			for _, child := range children {
+				walk(child)
+			}
+		}
+	}
+	walk(nodes)
+	return refs
+}
+
+// templateAssets возвращает ссылки шаблона на файлы static/ без повторов
+func templateAssets(nodes []tplNode) []string {
+	var assets []string
+	seen := make(map[string]bool)
+	var walk func(nodes []tplNode)
+	walk = func(nodes []tplNode) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *assetNode:
+				if !seen[n.key()] {
+					seen[n.key()] = true
+					assets = append(assets, n.key())
+				}
+			case *ifNode:
+				walk(n.then)
+				walk(n.els)
+			case *eachNode:
+				walk(n.body)
+				walk(n.els)
+			case *withNode:
+				walk(n.body)
+				walk(n.els)
+			case *sectionNode:
+				walk(n.body)
+			}
+		}
+	}
+	walk(nodes)
+	return assets
+}
+
+// isAssetName проверяет путь файла в {asset}: относительный, через "/", без "." и ".." в сегментах
+func isAssetName(s string) bool {
+	if s == "" || strings.ContainsAny(s, "\\\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(s, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// isTemplateName проверяет имя секции или, при withPath, имя шаблона, которое может содержать поддиректории
+func isTemplateName(s string, withPath bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-':
+		case c == '/' && withPath:
+		default:
+			return false
+		}
+	}
+	return !strings.Contains(s, "//") && s[0] != '/' && s[len(s)-1] != '/'
+}
+
+// parseTemplateVars собирает переменные, на которые ссылается шаблон вне циклов и блоков with.
+// Они регистрируются в модели страницы пустыми значениями, если страница их не задаёт;
+// для подстановки с фильтрами ({price|default:"n/a"}) регистрируется ключ без фильтров.
+func parseTemplateVars(nodes []tplNode) map[string]string {
+	vars := make(map[string]string)
+	var walk func(nodes []tplNode)
+	walk = func(nodes []tplNode) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *varNode:
+				if len(n.path) > 0 {
+					vars[strings.Join(n.path, ".")] = ""
+				}
+			case *ifNode:
+				walk(n.then)
+				walk(n.els)
+			case *eachNode:
+				walk(n.els)
+			case *withNode:
+				walk(n.els)
+			case *sectionNode:
+				walk(n.body)
+			}
+		}
+	}
+	walk(nodes)
+	return vars
+}
+
+// templateSet загружает шаблоны из директории и хранит разобранные шаблоны вместе с цепочкой наследования.
+// Набор используется всеми воркерами сборки: каждый шаблон читается и разбирается ровно один раз.
+type templateSet struct {
+	dir     string
+	entries sync.Map // Имя шаблона -> *templateEntry
+}
+
+// templateEntry — шаблон в наборе. Разбор файла и связывание с базовым шаблоном выполняются
+// отдельно, чтобы поиск циклов наследования не ждал чужих sync.Once.
+type templateEntry struct {
+	parseOnce sync.Once
+	linkOnce  sync.Once
+	tpl       *Template
+	parseErr  error
+	linkErr   error
+}
+
+// newTemplateSet создаёт набор шаблонов директории dir
+func newTemplateSet(dir string) *templateSet {
+	return &templateSet{dir: dir}
+}
+
+func (ts *templateSet) entry(name string) *templateEntry {
+	if e, ok := ts.entries.Load(name); ok {
+		return e.(*templateEntry)
+	}
+	e, _ := ts.entries.LoadOrStore(name, &templateEntry{})
+	return e.(*templateEntry)
+}
+
+// parsed читает и разбирает файл шаблона name без разрешения {extends}
+func (ts *templateSet) parsed(name string) (*Template, error) {
+	e := ts.entry(name)
+	e.parseOnce.Do(func() {
+		content, err := ioutil.ReadFile(filepath.Join(ts.dir, filepath.FromSlash(name)+".tpl"))
+		if err != nil {
+			e.parseErr = fmt.Errorf(msgErrorReadingTemplate, name, err)
+			return
+		}
+		e.tpl, e.parseErr = compileTemplate(name, string(content))
+	})
+	return e.tpl, e.parseErr
+}
+
+// loadTemplate возвращает шаблон и его переменные. Цепочка {extends} разрешается один раз,
+// и результат используется всеми страницами с этим шаблоном.
+func (ts *templateSet) loadTemplate(name string) (*Template, map[string]string, error) {
+	e := ts.entry(name)
+	e.linkOnce.Do(func() {
+		e.linkErr = ts.link(name)
+	})
+	if e.linkErr != nil {
+		return nil, nil, e.linkErr
+	}
+	return e.tpl, e.tpl.Vars, nil
+}
+
+// link связывает шаблон name с базовым шаблоном, предварительно проверив цепочку на циклы
+func (ts *templateSet) link(name string) error {
+	tpl, err := ts.parsed(name)
+	if err != nil {
+		return err
+	}
+
+	chain := []string{name}
+	for base := tpl.extends; base != ""; {
+		for _, other := range chain {
+			if other == base {
+				return fmt.Errorf("цикл наследования шаблонов: %s", strings.Join(append(chain, base), " -> "))
+			}
+		}
+		chain = append(chain, base)
+		parent, err := ts.parsed(base)
+		if err != nil {
+			return err
+		}
+		base = parent.extends
+	}
+
+	if tpl.extends != "" {
+		parent, parentVars, err := ts.loadTemplate(tpl.extends)
+		if err != nil {
+			return err
+		}
+		tpl.parent = parent
+		for k, v := range parentVars {
+			tpl.Vars[k] = v
+		}
+	}
+	return nil
+}
+
+// tplScope — область видимости при рендеринге: текущий элемент, локальные переменные и внешняя область
+type tplScope struct {
+	dot    interface{}
+	vars   map[string]interface{}
+	parent *tplScope
+}
+
+// lookup ищет значение по пути, начиная с самой внутренней области
+func (s *tplScope) lookup(path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return s.dot, true
+	}
+	for scope := s; scope != nil; scope = scope.parent {
+		if scope.vars != nil {
+			if v, ok := lookupValue(scope.vars, path); ok {
+				return v, true
+			}
+		}
+		if v, ok := lookupValue(scope.dot, path); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// lookupValue находит значение по пути внутри data.
+// Атрибуты страниц хранятся плоско ("site.title"), поэтому сначала проверяется составной ключ целиком.
+func lookupValue(data interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return data, true
+	}
+	switch d := data.(type) {
+	case *flatData:
+		return d.lookup(strings.Join(path, "."))
+	case map[string]string:
+		return lookupFlat(d, strings.Join(path, "."))
+	case map[string]interface{}:
+		if len(path) > 1 {
+			if v, ok := d[strings.Join(path, ".")]; ok {
+				return v, true
+			}
+		}
+		if v, ok := d[path[0]]; ok {
+			return lookupValue(v, path[1:])
+		}
+	case []interface{}:
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(d) {
+			return lookupValue(d[i], path[1:])
+		}
+	case []string:
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(d) {
+			return lookupValue(d[i], path[1:])
+		}
+	}
+	return nil, false
+}
+
+// lookupFlat находит значение key в плоском наборе атрибутов. Списки из front matter хранятся
+// как key.0, key.1, ..., а вложенные отображения — как key.<поле>; такие значения собираются обратно.
+// Список отображений хранится только составными ключами (items.0.title) и тоже распознаётся.
+// Пустое значение ищется перебором всех ключей, поэтому при рендеринге используется flatData.
+func lookupFlat(data map[string]string, key string) (interface{}, bool) {
+	if _, ok := data[key+".0"]; ok {
+		return flatList(data, key), true
+	}
+
+	value, ok := data[key]
+	if ok && value != "" {
+		return value, true
+	}
+	prefix := key + "."
+	sub := make(map[string]string)
+	first := false
+	for k, v := range data {
+		if strings.HasPrefix(k, prefix) {
+			sub[k[len(prefix):]] = v
+			first = first || strings.HasPrefix(k[len(prefix):], "0.")
+		}
+	}
+	if first {
+		return flatList(data, key), true
+	}
+	if len(sub) > 0 {
+		return sub, true
+	}
+	return value, ok
+}
+
+// flatList собирает элементы key.0, key.1, ... до первого отсутствующего
+func flatList(data map[string]string, key string) []interface{} {
+	var items []interface{}
+	for i := 0; ; i++ {
+		item, ok := lookupFlat(data, key+"."+strconv.Itoa(i))
+		if !ok {
+			break
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// flatData — плоский набор атрибутов страницы с индексом составных ключей. Индекс строится
+// один раз на рендеринг, поэтому поиск пустой или отсутствующей переменной не перебирает все атрибуты.
+type flatData struct {
+	data     map[string]string
+	children map[string][]string // Префикс ("author", "items.0") -> ключи, которые с него начинаются
+}
+
+func newFlatData(data map[string]string) *flatData {
+	children := make(map[string][]string)
+	for k := range data {
+		for i := 0; i < len(k); i++ {
+			if k[i] == '.' {
+				children[k[:i]] = append(children[k[:i]], k)
+			}
+		}
+	}
+	return &flatData{data: data, children: children}
+}
+
+// lookup работает как lookupFlat, но вложенные ключи берёт из индекса
+func (f *flatData) lookup(key string) (interface{}, bool) {
+	if _, ok := f.data[key+".0"]; ok {
+		return f.list(key), true
+	}
+	value, ok := f.data[key]
+	keys := f.children[key]
+	if ok && value != "" || len(keys) == 0 {
+		return value, ok
+	}
+	if len(f.children[key+".0"]) > 0 {
+		return f.list(key), true
+	}
+	sub := make(map[string]string, len(keys))
+	for _, k := range keys {
+		sub[k[len(key)+1:]] = f.data[k]
+	}
+	return sub, true
+}
+
+// list собирает элементы key.0, key.1, ... до первого отсутствующего
+func (f *flatData) list(key string) []interface{} {
+	var items []interface{}
+	for i := 0; ; i++ {
+		item, ok := f.lookup(key + "." + strconv.Itoa(i))
+		if !ok {
+			break
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// truthy определяет истинность значения в {if}: пустые строки, "0", "false", пустые списки ложны
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case string:
+		s := strings.TrimSpace(val)
+		return s != "" && s != "0" && s != "false"
+	case bool:
+		return val
+	case int:
+		return val != 0
+	case int64:
+		return val != 0
+	case float64:
+		return val != 0
+	case []interface{}:
+		return len(val) > 0
+	case []string:
+		return len(val) > 0
+	case map[string]interface{}:
+		return len(val) > 0
+	case map[string]string:
+		return len(val) > 0
+	}
+	return true
+}
+
+// listItems превращает значение в список для {each}. Строка делится по переводам строк,
+// а если их нет — по запятым; отображение перебирается в порядке ключей.
+func listItems(v interface{}) []interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return val
+	case []string:
+		items := make([]interface{}, len(val))
+		for i, s := range val {
+			items[i] = s
+		}
+		return items
+	case []map[string]string:
+		items := make([]interface{}, len(val))
+		for i, m := range val {
+			items[i] = m
+		}
+		return items
+	case string:
+		sep := ","
+		if strings.Contains(val, "\n") {
+			sep = "\n"
+		}
+		var items []interface{}
+		for _, part := range strings.Split(val, sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				items = append(items, part)
+			}
+		}
+		return items
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]interface{}, len(keys))
+		for i, k := range keys {
+			items[i] = map[string]interface{}{"key": k, "value": val[k]}
+		}
+		return items
+	}
+	return []interface{}{v}
+}
+
+// formatValue превращает значение в текст для подстановки. У отображения ({author} при
+// author.name в front matter) нет текстового вида, поэтому оно выводится пустой строкой,
+// а в списке пропускается.
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil, map[string]interface{}, map[string]string, *flatData:
+		return ""
+	case string:
+		return val
+	case []interface{}, []string, []map[string]string:
+		items := listItems(val)
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			switch item.(type) {
+			case map[string]interface{}, map[string]string:
+				continue
+			}
+			parts = append(parts, formatValue(item))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return formatConfigScalar(v)
+}
+
+// renderBuffers — буферы рендеринга, переиспользуемые между страницами
+var renderBuffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// Execute рендерит шаблон с данными data (обычно Model.Data).
+// Для наследника рендерится корневой базовый шаблон, а его секции берутся из самого производного шаблона.
+// Значения экранируются по месту подстановки; ключи из safe содержат доверенный HTML
+// и в тексте страницы выводятся как есть. {asset} и {integrity} ищут файлы в assets
+// (nil — файлов нет).
+func (t *Template) Execute(data interface{}, safe map[string]bool, assets *assetIndex) (string, error) {
+	buf := renderBuffers.Get().(*bytes.Buffer)
+	defer renderBuffers.Put(buf)
+	buf.Reset()
+	if err := t.executeTo(buf, data, safe, assets); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ExecuteBytes работает как Execute, но возвращает результат для записи в файл без лишнего копирования
+func (t *Template) ExecuteBytes(data interface{}, safe map[string]bool, assets *assetIndex) ([]byte, error) {
+	buf := renderBuffers.Get().(*bytes.Buffer)
+	defer renderBuffers.Put(buf)
+	buf.Reset()
+	if err := t.executeTo(buf, data, safe, assets); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// executeTo рендерит шаблон в buf
+func (t *Template) executeTo(buf *bytes.Buffer, data interface{}, safe map[string]bool, assets *assetIndex) error {
+	root := t
+	for root.parent != nil {
+		root = root.parent
+	}
+	if flat, ok := data.(map[string]string); ok {
+		data = newFlatData(flat)
+	}
+	e := &tplExec{tpl: t, buf: buf, safe: safe, assets: assets}
+	return e.execNodes(root.nodes, &tplScope{dot: data})
+}
+
+// tplExec хранит состояние одного рендеринга
+type tplExec struct {
+	tpl    *Template
+	buf    *bytes.Buffer
+	active map[string]bool // Секции, которые рендерятся сейчас
+	safe   map[string]bool
+	assets *assetIndex // Файлы static/ сборки для {asset} и {integrity}
+	ctx    escContext  // Контекст HTML в конце уже выведенного текста
+}
+
+// write выводит s и обновляет контекст экранирования
+func (e *tplExec) write(s string) {
+	e.buf.WriteString(s)
+	e.ctx = e.ctx.advance(s)
+}
+
+// writeValue применяет фильтры и выводит значение переменной с экранированием по контексту.
+// Доверенный HTML из safe выводится как есть, только если к нему не применялись фильтры.
+func (e *tplExec) writeValue(n *varNode, value interface{}) error {
+	s, err := applyFilters(formatValue(value), n.filters)
+	if err != nil {
+		return fmt.Errorf("шаблон %s: %s: %v", e.tpl.Name, n.raw, err)
+	}
+	trusted := len(n.filters) == 0 && len(n.path) > 0 && e.safe[strings.Join(n.path, ".")]
+	if n.unescaped || trusted && e.ctx.state == escText {
+		e.write(s)
+		return nil
+	}
+	e.write(e.ctx.escape(s))
+	return nil
+}
+
+// section возвращает тело секции name из ближайшего в цепочке наследования шаблона
+func (e *tplExec) section(name string) []tplNode {
+	for t := e.tpl; t != nil; t = t.parent {
+		if body, ok := t.sections[name]; ok {
+			return body
+		}
+	}
+	return nil
+}
+
+// execNodes рендерит последовательность узлов
+func (e *tplExec) execNodes(nodes []tplNode, scope *tplScope) error {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *textNode:
+			e.buf.WriteString(n.text)
+			e.ctx = n.advance(e.ctx)
+		case *varNode:
+			value, ok := scope.lookup(n.path)
+			if !ok && len(n.filters) == 0 {
+				// Неизвестная переменная без фильтров остаётся в выводе как есть
+				e.write(n.raw)
+				continue
+			}
+			if err := e.writeValue(n, value); err != nil {
+				return err
+			}
+		case *ifNode:
+			value, _ := scope.lookup(n.path)
+			branch := n.els
+			if truthy(value) != n.negate {
+				branch = n.then
+			}
+			if err := e.execNodes(branch, scope); err != nil {
+				return err
+			}
+		case *eachNode:
+			value, _ := scope.lookup(n.path)
+			items := listItems(value)
+			if len(items) == 0 {
+				if err := e.execNodes(n.els, scope); err != nil {
+					return err
+				}
+				continue
+			}
+			for i, item := range items {
+				loop := map[string]interface{}{
+					"index":  i,
+					"number": i + 1,
+					"first":  i == 0,
+					"last":   i == len(items)-1,
+				}
+				inner := &tplScope{dot: item, vars: map[string]interface{}{"loop": loop}, parent: scope}
+				if err := e.execNodes(n.body, inner); err != nil {
+					return err
+				}
+			}
+		case *withNode:
+			value, _ := scope.lookup(n.path)
+			if !truthy(value) {
+				if err := e.execNodes(n.els, scope); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.execNodes(n.body, &tplScope{dot: value, parent: scope}); err != nil {
+				return err
+			}
+		case *assetNode:
+			value, err := e.assets.resolve(n.name, n.integrity)
+			if err != nil {
+				return fmt.Errorf("шаблон %s: %s: %v", e.tpl.Name, n.raw, err)
+			}
+			e.write(e.ctx.escape(value))
+		case *sectionNode:
+			if e.active == nil {
+				e.active = make(map[string]bool)
+			}
+			if e.active[n.name] {
+				return fmt.Errorf("шаблон %s: секция %s включает саму себя", e.tpl.Name, n.name)
+			}
+			e.active[n.name] = true
+			err := e.execNodes(e.section(n.name), scope)
+			delete(e.active, n.name)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("шаблон %s: неизвестный узел %T", e.tpl.Name, node)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты языка шаблонов: поиск атрибутов, условия, циклы, блоки with и наследование.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlatDataLookup(t *testing.T) {
+	data := map[string]string{
+		"title":            "T",
+		"empty":            "",
+		"tags.0":           "go",
+		"tags.1":           "site",
+		"author.name":      "A",
+		"author.url":       "/a",
+		"links.0.name":     "x",
+		"links.0.url":      "/x",
+		"links.1.name":     "y",
+		"authorship":       "не поле author",
+		"author.social.gh": "a",
+	}
+	flat := newFlatData(data)
+	for _, key := range []string{"title", "empty", "missing", "tags", "author", "author.social", "links", "links.1", "author.name.x"} {
+		got, gotOK := flat.lookup(key)
+		want, wantOK := lookupFlat(data, key)
+		if gotOK != wantOK || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: получено %#v, %v; lookupFlat вернул %#v, %v", key, got, gotOK, want, wantOK)
+		}
+	}
+}
+
+func TestTemplateNestedAttributes(t *testing.T) {
+	tests := []struct {
+		name string
+		tpl  string
+		want string
+	}{
+		{"список", "{each tags}[{.}]{end}", "[go][site]"},
+		{"отображение", "{author.name} {author.url}", "A /a"},
+		{"список отображений", "{each links}{name}:{url};{end}", "x:/x;y:{url};"},
+		{"пустое и отсутствующее", "[{empty}][{missing}]", "[][{missing}]"},
+		{"условие по отображению", "{if author}есть{else}нет{end} {if nobody}есть{else}нет{end}", "есть нет"},
+		{"отображение целиком", "[{author}] [{author|upper}]", "[] []"},
+		{"список отображений целиком", "[{links}] [{tags}]", "[] [go, site]"},
+	}
+	data := map[string]string{
+		"tags.0": "go", "tags.1": "site",
+		"author.name": "A", "author.url": "/a",
+		"links.0.name": "x", "links.0.url": "/x", "links.1.name": "y",
+		"empty": "",
+	}
+	for _, tt := range tests {
+		tpl, err := compileTemplate("test", tt.tpl)
+		if err != nil {
+			t.Errorf("%s: ошибка разбора %v", tt.name, err)
+			continue
+		}
+		got, err := tpl.Execute(data, nil, nil)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: получено %q, ожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateAssetIndex(t *testing.T) {
+	tpl, err := compileTemplate("test", `<link href="{asset "css/a.css"}">`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := &assetIndex{files: map[string]*staticFile{"css/a.css": {File: "css/a.0123abcd.css"}}}
+	got, err := tpl.Execute(map[string]string{}, nil, assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<link href="/css/a.0123abcd.css">`; got != want {
+		t.Errorf("получено %q, ожидалось %q", got, want)
+	}
+	// Индекс передаётся в каждый рендеринг, а не хранится глобально
+	if _, err := tpl.Execute(map[string]string{}, nil, nil); err == nil {
+		t.Error("без индекса файлов ожидалась ошибка")
+	}
+}
+
+func TestTemplateControlFlow(t *testing.T) {
+	tests := []struct {
+		name string
+		tpl  string
+		want string
+	}{
+		{"истинное условие", "{if title}да{end}", "да"},
+		{"ложное условие с else", "{if draft}да{else}нет{end}", "нет"},
+		{"отсутствующий ключ в условии", "{if missing}да{else}нет{end}", "нет"},
+		{"отрицание", "{if not missing}да{end}", "да"},
+		{"ноль и false ложны", "{if zero}1{end}{if off}2{end}{if blank}3{end}", ""},
+		{"цикл по строке с запятыми", "{each tags}<{.}>{end}", "<go><site>"},
+		{"переменные цикла", "{each tags}{loop.number}/{loop.index}{if loop.first}^{end}{if loop.last}${end};{end}", "1/0^;2/1$;"},
+		{"пустой список", "{each none}x{else}пусто{end}", "пусто"},
+		{"отсутствующий список", "{each missing}x{else}пусто{end}", "пусто"},
+		{"внешняя область в цикле", "{each tags}{title}:{.};{end}", "T:go;T:site;"},
+		{"вложенные циклы", "{each groups}{name}[{each items}{.}{if not loop.last},{end}{end}]{end}", "a[1,2]b[]"},
+		{"with меняет контекст", "{with author}{name} <{url}>{end}", "A </a>"},
+		{"with видит внешнюю область", "{with author}{name} — {title}{end}", "A — T"},
+		{"with для отсутствующего ключа", "{with missing}x{else}нет{end}", "нет"},
+		{"условие внутри with", "{with author}{if url}{url}{else}-{end}{end}", "/a"},
+		{"неизвестная переменная в цикле", "{each tags}{nope}{end}", "{nope}{nope}"},
+		{"фигурные скобки CSS", "p { color: red; }{if title}!{end}", "p { color: red; }!"},
+	}
+	data := map[string]interface{}{
+		"title": "T",
+		"draft": "false",
+		"zero":  "0",
+		"off":   false,
+		"blank": "  ",
+		"tags":  "go, site",
+		"none":  []interface{}{},
+		"author": map[string]interface{}{
+			"name": "A",
+			"url":  "/a",
+		},
+		"groups": []interface{}{
+			map[string]interface{}{"name": "a", "items": []interface{}{"1", "2"}},
+			map[string]interface{}{"name": "b", "items": []interface{}{}},
+		},
+	}
+	for _, tt := range tests {
+		tpl, err := compileTemplate("test", tt.tpl)
+		if err != nil {
+			t.Errorf("%s: ошибка разбора %v", tt.name, err)
+			continue
+		}
+		got, err := tpl.Execute(data, nil, nil)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: получено %q, ожидалось %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateControlFlowErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tpl  string
+		want string
+	}{
+		{"незакрытое условие", "{if a}x", "не закрыт тегом {end}"},
+		{"незакрытый цикл после else", "{each a}x{else}y", "не закрыт тегом {end}"},
+		{"повторный else", "{if a}x{else}y{else}z{end}", "повторный {else}"},
+		{"лишний end", "x{end}", "лишний тег"},
+		{"else вне блока", "{else}", "лишний тег"},
+	}
+	for _, tt := range tests {
+		_, err := compileTemplate("test", tt.tpl)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestTemplateInheritance(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base":      "<title>{section title}Сайт{end}</title><main>{section body}пусто{end}</main><footer>{section footer}©{end}</footer>",
+		"page":      "{extends base}{section body}<h1>{title}</h1>{section content}{content}{end}{end}игнорируется",
+		"docs/page": "{extends page}{section title}{title} — документация{end}{section content}<article>{content}</article>{end}",
+		"loop/a":    "{extends loop/b}",
+		"loop/b":    "{extends loop/c}",
+		"loop/c":    "{extends loop/a}",
+		"orphan":    "{extends missing}",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name)+".tpl")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := newTemplateSet(dir)
+	data := map[string]string{"title": "T", "content": "C"}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"base", "<title>Сайт</title><main>пусто</main><footer>©</footer>"},
+		{"page", "<title>Сайт</title><main><h1>T</h1>C</main><footer>©</footer>"},
+		{"docs/page", "<title>T — документация</title><main><h1>T</h1><article>C</article></main><footer>©</footer>"},
+	}
+	for _, tt := range tests {
+		tpl, vars, err := ts.loadTemplate(tt.name)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		got, err := tpl.Execute(data, nil, nil)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: получено %q, ожидалось %q", tt.name, got, tt.want)
+		}
+		if _, ok := vars["title"]; tt.name != "base" && !ok {
+			t.Errorf("%s: переменные базового шаблона не унаследованы: %v", tt.name, vars)
+		}
+	}
+	// Шаблон разбирается один раз и используется всеми страницами
+	if first, _, _ := ts.loadTemplate("docs/page"); first == nil || first.parent == nil || first.parent.parent == nil {
+		t.Error("цепочка наследования docs/page не связана")
+	}
+
+	errs := []struct {
+		name string
+		want string
+	}{
+		{"loop/b", "цикл наследования шаблонов: loop/b -> loop/c -> loop/a -> loop/b"},
+		{"orphan", "missing"},
+	}
+	for _, tt := range errs {
+		if _, _, err := ts.loadTemplate(tt.name); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.want)
+		}
+	}
+	if _, err := compileTemplate("twice", "{section a}{end}{section a}{end}"); err == nil || !strings.Contains(err.Error(), "секция a объявлена дважды") {
+		t.Errorf("повторная секция: ошибка %v", err)
+	}
+	if _, err := compileTemplate("nested", "{if x}{extends base}{end}"); err == nil {
+		t.Error("вложенный {extends}: ожидалась ошибка")
+	}
+}
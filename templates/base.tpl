@@ -0,0 +1,11 @@
+<html lang="{site.language}">
+	<head>
+		<title>{section title}{title} — {site.title}{end}</title>
+	</head>
+<body>
+	<h1>{title}</h1>
+	<main>
+	{section main}{content|raw}{end}
+	</main>
+</body>
+</html>
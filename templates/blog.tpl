@@ -1,11 +1 @@
-<html>
-	<head>
-		<title>{title}</title>
-	</head>
-<body>
-	<h1>{title}</h1>
-	<main>
-	{content}
-	</main>
-</body>
-</html>
+{extends base}
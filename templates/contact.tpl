@@ -1,12 +1,6 @@
-<html>
-	<head>
-		<title>{title}</title>
-	</head>
-<body>
-	<h1>{title}</h1>
-	<main>
-	Email:{email}
-	phone:{phone}
-	</main>
-</body>
-</html>
+{extends base}
+
+{section main}
+	{if email}Email:{email}{end}
+	{if phone}phone:{phone}{else}phone: не указан{end}
+{end}
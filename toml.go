@@ -0,0 +1,554 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Разбор подмножества TOML для файла конфигурации сайта.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// tomlFloat — грамматика дробного числа TOML: ключевые слова inf и nan со знаком
+	// или десятичное число с дробной частью и/или порядком; подчёркивания только между цифрами
+	tomlFloat = regexp.MustCompile(`^[+-]?(?:inf|nan|[0-9](?:_?[0-9])*(?:\.[0-9](?:_?[0-9])*)?(?:[eE][+-]?[0-9](?:_?[0-9])*)?)$`)
+	// tomlDateTime — начало даты (1979-05-27) или времени (07:32:00)
+	tomlDateTime = regexp.MustCompile(`^(?:[0-9]{4}-[0-9]{2}-[0-9]{2}|[0-9]{2}:[0-9]{2})`)
+)
+
+// tomlParser разбирает TOML-документ в map[string]interface{}.
+// Поддерживаются таблицы, массивы таблиц, точечные ключи, строки всех видов,
+// числа, логические значения, массивы и встроенные таблицы. Даты возвращаются строками.
+type tomlParser struct {
+	src     string
+	pos     int
+	line    int
+	defined map[string]bool // Таблицы (адрес map), уже заданные заголовком или встроенной таблицей
+}
+
+// parseTOML разбирает текст TOML и возвращает корневую таблицу
+func parseTOML(src string) (map[string]interface{}, error) {
+	p := &tomlParser{src: src, line: 1, defined: make(map[string]bool)}
+	root := make(map[string]interface{})
+	current := root
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			table, err := p.parseHeader(root)
+			if err != nil {
+				return nil, err
+			}
+			current = table
+		} else {
+			if err := p.parseKeyValue(current); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := p.expectLineEnd(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml, строка %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(p.src[p.pos:], prefix)
+}
+
+// skipSpaces пропускает пробелы и табуляции в пределах строки
+func (p *tomlParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment пропускает комментарий до конца строки
+func (p *tomlParser) skipComment() {
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank пропускает пустые строки, пробелы и комментарии
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// expectLineEnd проверяет, что после значения до конца строки нет ничего, кроме комментария
+func (p *tomlParser) expectLineEnd() error {
+	p.skipSpaces()
+	p.skipComment()
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return p.errorf("неожиданный символ %q", p.peek())
+	}
+	p.pos++
+	p.line++
+	return nil
+}
+
+// parseHeader разбирает заголовок [table] или [[array]] и возвращает текущую таблицу
+func (p *tomlParser) parseHeader(root map[string]interface{}) (map[string]interface{}, error) {
+	isArray := p.hasPrefix("[[")
+	if isArray {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipSpaces()
+	path, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !p.hasPrefix(closing) {
+		return nil, p.errorf("ожидалось %q в заголовке таблицы", closing)
+	}
+	p.pos += len(closing)
+
+	if !isArray {
+		table, err := p.table(root, path)
+		if err != nil {
+			return nil, err
+		}
+		// Таблицу можно задать заголовком только один раз; неявно созданные
+		// заголовками вложенных таблиц уровни заголовок ещё получить может
+		id := fmt.Sprintf("%p", table)
+		if p.defined[id] {
+			return nil, p.errorf("таблица %q определена повторно", strings.Join(path, "."))
+		}
+		p.defined[id] = true
+		return table, nil
+	}
+
+	parent, err := p.table(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	table := make(map[string]interface{})
+	switch existing := parent[last].(type) {
+	case nil:
+		parent[last] = []interface{}{table}
+	case []interface{}:
+		parent[last] = append(existing, table)
+	default:
+		return nil, p.errorf("ключ %q уже определён и не является массивом таблиц", last)
+	}
+	return table, nil
+}
+
+// table возвращает таблицу по пути, создавая недостающие уровни
+func (p *tomlParser) table(root map[string]interface{}, path []string) (map[string]interface{}, error) {
+	current := root
+	for _, key := range path {
+		switch value := current[key].(type) {
+		case nil:
+			next := make(map[string]interface{})
+			current[key] = next
+			current = next
+		case map[string]interface{}:
+			current = value
+		case []interface{}:
+			if len(value) == 0 {
+				return nil, p.errorf("ключ %q не является таблицей", key)
+			}
+			last, ok := value[len(value)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("ключ %q не является таблицей", key)
+			}
+			current = last
+		default:
+			return nil, p.errorf("ключ %q не является таблицей", key)
+		}
+	}
+	return current, nil
+}
+
+// parseKeyValue разбирает строку вида key = value и сохраняет значение в table
+func (p *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	path, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpaces()
+	if p.peek() != '=' {
+		return p.errorf("ожидался знак '=' после ключа %q", strings.Join(path, "."))
+	}
+	p.pos++
+	p.skipSpaces()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.table(table, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	if _, exists := parent[last]; exists {
+		return p.errorf("ключ %q определён повторно", strings.Join(path, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey разбирает простой, строковый или точечный ключ
+func (p *tomlParser) parseKey() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpaces()
+		var part string
+		switch p.peek() {
+		case '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		case '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isTOMLBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("ожидался ключ")
+			}
+			part = p.src[start:p.pos]
+		}
+		path = append(path, part)
+
+		p.skipSpaces()
+		if p.peek() != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// parseValue разбирает значение любого поддерживаемого типа
+func (p *tomlParser) parseValue() (interface{}, error) {
+	switch {
+	case p.hasPrefix(`"""`):
+		return p.parseMultilineString(`"""`, true)
+	case p.hasPrefix(`'''`):
+		return p.parseMultilineString(`'''`, false)
+	case p.peek() == '"':
+		return p.parseBasicString()
+	case p.peek() == '\'':
+		return p.parseLiteralString()
+	case p.peek() == '[':
+		return p.parseArray()
+	case p.peek() == '{':
+		return p.parseInlineTable()
+	case p.hasKeyword("true"):
+		p.pos += 4
+		return true, nil
+	case p.hasKeyword("false"):
+		p.pos += 5
+		return false, nil
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(",]}#\r\n", rune(p.peek())) {
+		p.pos++
+	}
+	token := strings.TrimSpace(p.src[start:p.pos])
+	p.pos = start + len(strings.TrimRight(p.src[start:p.pos], " \t"))
+	if token == "" {
+		return nil, p.errorf("ожидалось значение")
+	}
+
+	clean := strings.ReplaceAll(token, "_", "")
+	if i, err := strconv.ParseInt(clean, 0, 64); err == nil {
+		if hasLeadingZero(clean) {
+			return nil, p.errorf("ведущие нули в числе %q не допускаются", token)
+		}
+		return i, nil
+	}
+	if tomlFloat.MatchString(token) {
+		switch strings.TrimLeft(token, "+-") {
+		case "inf":
+			if token[0] == '-' {
+				return math.Inf(-1), nil
+			}
+			return math.Inf(1), nil
+		case "nan":
+			return math.NaN(), nil
+		}
+		if hasLeadingZero(clean) {
+			return nil, p.errorf("ведущие нули в числе %q не допускаются", token)
+		}
+		if f, err := strconv.ParseFloat(clean, 64); err == nil {
+			return f, nil
+		}
+	}
+	if tomlDateTime.MatchString(token) {
+		// Дата или время: сохраняем в исходном виде
+		return token, nil
+	}
+	return nil, p.errorf("неизвестное значение %q", token)
+}
+
+// hasKeyword сообщает, что с текущей позиции идёт слово word, за которым значение заканчивается:
+// trueish — не логическое значение
+func (p *tomlParser) hasKeyword(word string) bool {
+	if !p.hasPrefix(word) {
+		return false
+	}
+	end := p.pos + len(word)
+	return end == len(p.src) || strings.IndexByte(" \t,]}#\r\n", p.src[end]) >= 0
+}
+
+// hasLeadingZero сообщает, что десятичное число начинается с лишнего нуля: 010, -01.5.
+// Префиксы 0x, 0o и 0b сюда не относятся.
+func hasLeadingZero(number string) bool {
+	digits := strings.TrimLeft(number, "+-")
+	return len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9'
+}
+
+// parseBasicString разбирает строку в двойных кавычках с escape-последовательностями
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("незакрытая строка")
+		}
+		c := p.peek()
+		if c == '"' {
+			p.pos++
+			return sb.String(), nil
+		}
+		if c == '\\' {
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+			continue
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+}
+
+// parseEscape разбирает escape-последовательность, начинающуюся с обратной косой черты
+func (p *tomlParser) parseEscape(sb *strings.Builder) error {
+	p.pos++
+	if p.eof() {
+		return p.errorf("незаконченная escape-последовательность")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'b':
+		sb.WriteByte('\b')
+	case 'f':
+		sb.WriteByte('\f')
+	case '"', '\\':
+		sb.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorf("неверная escape-последовательность \\%c", c)
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil {
+			return p.errorf("неверная escape-последовательность \\%c", c)
+		}
+		p.pos += size
+		sb.WriteRune(rune(code))
+	default:
+		return p.errorf("неизвестная escape-последовательность \\%c", c)
+	}
+	return nil
+}
+
+// parseLiteralString разбирает строку в одинарных кавычках без обработки escape-последовательностей
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("незакрытая строка")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// parseMultilineString разбирает многострочную строку, ограниченную delim
+func (p *tomlParser) parseMultilineString(delim string, escapes bool) (string, error) {
+	p.pos += len(delim)
+	// Перевод строки сразу после открывающих кавычек не входит в значение
+	if p.hasPrefix("\r\n") {
+		p.pos += 2
+		p.line++
+	} else if p.hasPrefix("\n") {
+		p.pos++
+		p.line++
+	}
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("незакрытая многострочная строка")
+		}
+		if p.hasPrefix(delim) {
+			p.pos += len(delim)
+			return sb.String(), nil
+		}
+		c := p.peek()
+		if escapes && c == '\\' {
+			// Обратная косая черта в конце строки склеивает строки
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t\r")
+			if strings.HasPrefix(rest, "\n") {
+				p.pos++
+				for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+					if p.peek() == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if c == '\n' {
+			p.line++
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+}
+
+// parseArray разбирает массив значений, допускающий переносы строк и комментарии
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.pos++
+	values := make([]interface{}, 0)
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return values, nil
+		default:
+			return nil, p.errorf("ожидалась ',' или ']' в массиве")
+		}
+	}
+}
+
+// parseInlineTable разбирает встроенную таблицу вида { key = value, ... }
+func (p *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	p.pos++
+	table := make(map[string]interface{})
+	// Встроенная таблица задана полностью, дополнить её заголовком нельзя
+	p.defined[fmt.Sprintf("%p", table)] = true
+	p.skipSpaces()
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		p.skipSpaces()
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("ожидалась ',' или '}' во встроенной таблице")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты разбора TOML.
+*/
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]interface{}
+	}{
+		{"пустой документ", "# только комментарий\n", map[string]interface{}{}},
+		{"скаляры", "s = \"a\\tb\"\nl = 'c:\\path'\ni = 1_000\nh = 0x1F\no = 0o17\nf = -1.5\nz = 0\nzf = 0.5\nb = true\nn = false # комментарий\n",
+			map[string]interface{}{"s": "a\tb", "l": `c:\path`, "i": int64(1000), "h": int64(31), "o": int64(15),
+				"f": -1.5, "z": int64(0), "zf": 0.5, "b": true, "n": false}},
+		{"дробные числа", "a = 1_000.5\nb = 1e3\nc = -2.5E-2\nd = +3.0e+1_0\ne = inf\nf = -inf\n",
+			map[string]interface{}{"a": 1000.5, "b": 1000.0, "c": -0.025, "d": 3e10, "e": math.Inf(1), "f": math.Inf(-1)}},
+		{"даты строками", "d = 2025-07-02\nt = 07:30:00\n", map[string]interface{}{"d": "2025-07-02", "t": "07:30:00"}},
+		{"многострочные строки", "a = \"\"\"\nx\\\n   y\"\"\"\nb = '''\nraw\\n'''\n", map[string]interface{}{"a": "xy", "b": "raw\\n"}},
+		{"таблицы и точечные ключи", "[site]\ntitle = \"T\"\nauthor.name = \"A\"\n[site.extra]\nk = 1\n",
+			map[string]interface{}{"site": map[string]interface{}{"title": "T", "author": map[string]interface{}{"name": "A"},
+				"extra": map[string]interface{}{"k": int64(1)}}}},
+		{"неявная таблица задаётся позже", "[a.b]\nx = 1\n[a]\ny = 2\n",
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"x": int64(1)}, "y": int64(2)}}},
+		{"массивы", "a = [\n  1, # первый\n  2,\n]\nb = []\nc = [\"x\", [true]]\n",
+			map[string]interface{}{"a": []interface{}{int64(1), int64(2)}, "b": []interface{}{}, "c": []interface{}{"x", []interface{}{true}}}},
+		{"встроенная таблица", "p = { x = 1, y = \"z\" }\n", map[string]interface{}{"p": map[string]interface{}{"x": int64(1), "y": "z"}}},
+		{"массив таблиц", "[[items]]\nn = 1\n[items.sub]\nk = 1\n[[items]]\nn = 2\n[items.sub]\nk = 2\n",
+			map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"n": int64(1), "sub": map[string]interface{}{"k": int64(1)}},
+				map[string]interface{}{"n": int64(2), "sub": map[string]interface{}{"k": int64(2)}},
+			}}},
+	}
+	for _, tt := range tests {
+		got, err := parseTOML(tt.src)
+		if err != nil {
+			t.Errorf("%s: ошибка %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\nполучено  %#v\nожидалось %#v", tt.name, got, tt.want)
+		}
+	}
+
+	// NaN не равен самому себе, поэтому проверяется отдельно
+	got, err := parseTOML("a = nan\nb = +nan\nc = -nan\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if f, ok := got[key].(float64); !ok || !math.IsNaN(f) {
+			t.Errorf("%s: получено %#v, ожидалось NaN", key, got[key])
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // Фрагмент текста ошибки
+	}{
+		{"повторная таблица", "[site]\na = 1\n\n[site]\nb = 2\n", "строка 4: таблица \"site\" определена повторно"},
+		{"повторная вложенная таблица", "[a.b]\n[a]\n[a.b]\n", "строка 3: таблица \"a.b\" определена повторно"},
+		{"заголовок встроенной таблицы", "p = { x = 1 }\n[p]\n", "строка 2: таблица \"p\" определена повторно"},
+		{"повторный ключ", "a = 1\na = 2\n", "строка 2: ключ \"a\" определён повторно"},
+		{"ведущий ноль", "x = 1\nn = 010\n", "строка 2: ведущие нули в числе \"010\""},
+		{"ведущий ноль со знаком", "n = -01\n", "строка 1: ведущие нули"},
+		{"ведущий ноль в дробном", "n = 00.5\n", "строка 1: ведущие нули"},
+		{"Infinity", "n = Infinity\n", "строка 1: неизвестное значение \"Infinity\""},
+		{"шестнадцатеричное дробное", "n = 0x1p-2\n", "неизвестное значение \"0x1p-2\""},
+		{"двойное подчёркивание", "n = 1__0.5\n", "неизвестное значение"},
+		{"подчёркивание перед точкой", "n = 1_.5\n", "неизвестное значение"},
+		{"подчёркивание в начале", "n = _1.0\n", "неизвестное значение"},
+		{"нет целой части", "n = .5\n", "неизвестное значение"},
+		{"нет дробной части", "n = 5.\n", "неизвестное значение"},
+		{"пустой порядок", "n = 1e\n", "неизвестное значение"},
+		{"регистр nan", "n = NaN\n", "неизвестное значение"},
+		{"префикс true", "\nb = trueish\n", "строка 2: неизвестное значение \"trueish\""},
+		{"префикс false", "b = [falsey]\n", "строка 1: неизвестное значение \"falsey\""},
+		{"незакрытая строка", "s = \"abc\n", "строка 1: незакрытая строка"},
+		{"лишнее после значения", "a = \"x\" 2\n", "строка 1: неожиданный символ"},
+		{"нет знака равенства", "a 1\n", "ожидался знак '='"},
+		{"незакрытый заголовок", "[site\n", "ожидалось \"]\""},
+	}
+	for _, tt := range tests {
+		_, err := parseTOML(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: ошибка %v, ожидалась содержащая %q", tt.name, err, tt.want)
+		}
+	}
+}
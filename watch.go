@@ -0,0 +1,261 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Режим наблюдения: пересборка сайта при изменении исходников.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Сообщения режима наблюдения
+const (
+	msgWatching       = "Наблюдение за изменениями в %s (Ctrl+C для выхода)\n"
+	msgWatchChanged   = "Изменено файлов: %d (%s), пересборка...\n"
+	msgErrorWatchScan = "Ошибка при обходе директории %s: %v\n"
+	msgWatchFailed    = "Сайт собран с ошибками, наблюдение продолжается"
+)
+
+// fileStamp — состояние файла, по которому опрос замечает изменения
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+// pageCache хранит страницы, разобранные processPage, между пересборками в режиме наблюдения.
+// Страница читается заново, только если изменились её файлы, блоки или конфигурация.
+type pageCache struct {
+	mu    sync.Mutex
+	key   string // Отпечаток блоков и настроек, с которыми разобраны страницы
+	pages map[string]*Model
+}
+
+func newPageCache() *pageCache {
+	return &pageCache{pages: make(map[string]*Model)}
+}
+
+// reset очищает кэш, если страницы были разобраны с другими блоками или настройками
+func (c *pageCache) reset(key string) {
+	c.mu.Lock()
+	if c.key != key {
+		c.key = key
+		c.pages = make(map[string]*Model)
+	}
+	c.mu.Unlock()
+}
+
+// invalidate удаляет из кэша страницу, которой принадлежит изменившийся файл path:
+// однофайловую страницу или директорию страницы
+func (c *pageCache) invalidate(path string) {
+	c.mu.Lock()
+	delete(c.pages, path)
+	delete(c.pages, filepath.Dir(path))
+	c.mu.Unlock()
+}
+
+// load возвращает страницу pagePath из кэша или разбирает её через processPage.
+// Вызывающий получает копию и может дополнять её атрибуты. Без кэша страница всегда читается с диска.
+func (c *pageCache) load(contentDir, pagePath string, blocks map[string]string, rawHTML bool) (*Model, error) {
+	if c == nil {
+		return processPage(contentDir, pagePath, blocks, rawHTML)
+	}
+	c.mu.Lock()
+	cached, ok := c.pages[pagePath]
+	c.mu.Unlock()
+	if ok {
+		return cached.clone(), nil
+	}
+
+	model, err := processPage(contentDir, pagePath, blocks, rawHTML)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.pages[pagePath] = model.clone()
+	c.mu.Unlock()
+	return model, nil
+}
+
+// clone возвращает копию модели с собственными картами атрибутов
+func (m *Model) clone() *Model {
+	copied := *m
+	copied.Data = make(map[string]string, len(m.Data))
+	for k, v := range m.Data {
+		copied.Data[k] = v
+	}
+	copied.Safe = make(map[string]bool, len(m.Safe))
+	for k, v := range m.Safe {
+		copied.Safe[k] = v
+	}
+	copied.Dates = make(map[string]time.Time, len(m.Dates))
+	for k, v := range m.Dates {
+		copied.Dates[k] = v
+	}
+	return &copied
+}
+
+// siteWatcher опрашивает директории исходников и пересобирает сайт после серии изменений
+type siteWatcher struct {
+	opts     *Options
+	interval time.Duration // Период опроса файлов
+	debounce time.Duration // Пауза без изменений, после которой начинается пересборка
+	cache    *pageCache
+	stamps   map[string]fileStamp
+}
+
+func newSiteWatcher(opts *Options, interval, debounce time.Duration) *siteWatcher {
+	return &siteWatcher{opts: opts, interval: interval, debounce: debounce, cache: newPageCache()}
+}
+
+// dirs возвращает директории, за которыми ведётся наблюдение
+func (w *siteWatcher) dirs() []string {
+	return []string{w.opts.ContentDir, w.opts.TemplatesDir, w.opts.BlocksDir, w.opts.CollectionsDir, w.opts.StaticDir}
+}
+
+// scan обходит директории исходников и файл конфигурации и возвращает состояние всех файлов.
+// Директория сборки пропускается, даже если она вложена в одну из наблюдаемых.
+func (w *siteWatcher) scan() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	output := filepath.Clean(w.opts.OutputDir)
+	for _, dir := range w.dirs() {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if info.IsDir() {
+				if filepath.Clean(path) == output {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			stamps[path] = fileStamp{modTime: info.ModTime().UnixNano(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, msgErrorWatchScan, dir, err)
+		}
+	}
+	if w.opts.ConfigPath != "" {
+		if info, err := os.Stat(w.opts.ConfigPath); err == nil {
+			stamps[w.opts.ConfigPath] = fileStamp{modTime: info.ModTime().UnixNano(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// changedFiles возвращает отсортированный список файлов, которые появились, изменились или исчезли
+func changedFiles(prev, next map[string]fileStamp) []string {
+	var changed []string
+	for path, stamp := range next {
+		if old, ok := prev[path]; !ok || old != stamp {
+			changed = append(changed, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// build пересобирает сайт. Страницы, файлы которых не менялись, берутся из кэша,
+// а рендерятся только те, чьи входные данные отличаются от манифеста предыдущей сборки.
+func (w *siteWatcher) build() *BuildReport {
+	return runBuild(w.opts, w.cache)
+}
+
+// apply учитывает изменившиеся файлы перед пересборкой: сбрасывает страницы в кэше
+// и перечитывает конфигурацию, если изменилась она. Страницы сбрасываются до чтения конфигурации,
+// чтобы правки из той же серии не потерялись, если конфигурация содержит ошибку.
+func (w *siteWatcher) apply(changed []string) error {
+	configChanged := false
+	for _, path := range changed {
+		if path == w.opts.ConfigPath {
+			configChanged = true
+			continue
+		}
+		w.cache.invalidate(path)
+	}
+	if !configChanged {
+		return nil
+	}
+	cfg, err := loadConfig(w.opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+	w.opts.applyConfig(cfg)
+	return nil
+}
+
+// run выполняет первую сборку и затем опрашивает файлы, пока не закрыт stop.
+// После каждой сборки вызывается rebuilt с её отчётом.
+func (w *siteWatcher) run(stop <-chan struct{}, rebuilt func(*BuildReport)) {
+	w.stamps = w.scan()
+	rebuilt(w.build())
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var pending []string
+	var lastChange time.Time
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			stamps := w.scan()
+			if changed := changedFiles(w.stamps, stamps); len(changed) > 0 {
+				w.stamps = stamps
+				pending = append(pending, changed...)
+				lastChange = now
+				continue
+			}
+			// Серия правок собирается в одну пересборку после паузы debounce
+			if len(pending) == 0 || now.Sub(lastChange) < w.debounce {
+				continue
+			}
+			changed := uniqueStrings(pending)
+			pending = nil
+			fmt.Printf(msgWatchChanged, len(changed), summarizePaths(changed, 3))
+			if err := w.apply(changed); err != nil {
+				fmt.Fprintf(os.Stderr, msgErrorConfig, err)
+				continue
+			}
+			rebuilt(w.build())
+		}
+	}
+}
+
+// uniqueStrings возвращает отсортированные уникальные строки
+func uniqueStrings(values []string) []string {
+	sort.Strings(values)
+	unique := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// summarizePaths перечисляет не больше limit путей для сообщения о пересборке
+func summarizePaths(paths []string, limit int) string {
+	if len(paths) <= limit {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s и ещё %d", strings.Join(paths[:limit], ", "), len(paths)-limit)
+}
@@ -0,0 +1,193 @@
+/*
+Author: Артем Назаров
+Email: programmist.nazarov@gmail.com
+Created: 2025-07-02
+Description: Тесты режима наблюдения: кэш страниц и поиск изменившихся файлов.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestChangedFiles(t *testing.T) {
+	prev := map[string]fileStamp{
+		"a": {modTime: 1, size: 1},
+		"b": {modTime: 1, size: 1},
+		"c": {modTime: 1, size: 1},
+		"d": {modTime: 1, size: 1},
+	}
+	next := map[string]fileStamp{
+		"a": {modTime: 1, size: 1}, // без изменений
+		"b": {modTime: 2, size: 1}, // новое время
+		"c": {modTime: 1, size: 2}, // новый размер
+		"e": {modTime: 1, size: 1}, // новый файл; d удалён
+	}
+	if got, want := changedFiles(prev, next), []string{"b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %v, ожидалось %v", got, want)
+	}
+	if got := changedFiles(next, next); len(got) != 0 {
+		t.Errorf("без изменений: получено %v", got)
+	}
+}
+
+func TestSiteWatcherScanSkipsOutput(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string) string {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	want := []string{
+		write("content/a/title.val"),
+		write("templates/page.tpl"),
+		write("static/css/site.css"),
+		write("goferret.toml"),
+	}
+	// Сборка внутри наблюдаемой директории не вызывает бесконечных пересборок
+	write("content/build/a.html")
+
+	opts := &Options{
+		ContentDir:     filepath.Join(root, "content"),
+		TemplatesDir:   filepath.Join(root, "templates"),
+		BlocksDir:      filepath.Join(root, "blocks"),
+		CollectionsDir: filepath.Join(root, "collections"),
+		StaticDir:      filepath.Join(root, "static"),
+		OutputDir:      filepath.Join(root, "content", "build"),
+		ConfigPath:     filepath.Join(root, "goferret.toml"),
+	}
+	stamps := newSiteWatcher(opts, 0, 0).scan()
+	var got []string
+	for path := range stamps {
+		got = append(got, path)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("получено %v, ожидалось %v", got, want)
+	}
+}
+
+func TestPageCache(t *testing.T) {
+	contentDir := t.TempDir()
+	pageDir := filepath.Join(contentDir, "a")
+	titleFile := filepath.Join(pageDir, "title.val")
+	if err := os.MkdirAll(pageDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	setTitle := func(title string) {
+		if err := os.WriteFile(titleFile, []byte(title), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func(cache *pageCache) string {
+		model, err := cache.load(contentDir, pageDir, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return model.Data["title"]
+	}
+	setTitle("A")
+	cache := newPageCache()
+	cache.reset("v1")
+
+	first, err := cache.load(contentDir, pageDir, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Вызывающий получает копию: изменения модели не попадают в кэш
+	first.Data["title"] = "изменено"
+	setTitle("B")
+	if got := load(cache); got != "A" {
+		t.Errorf("из кэша: получено %q, ожидалось A", got)
+	}
+
+	cache.invalidate(titleFile)
+	if got := load(cache); got != "B" {
+		t.Errorf("после изменения файла: получено %q, ожидалось B", got)
+	}
+
+	setTitle("C")
+	cache.reset("v1")
+	if got := load(cache); got != "B" {
+		t.Errorf("тот же ключ: получено %q, ожидалось B", got)
+	}
+	cache.reset("v2")
+	if got := load(cache); got != "C" {
+		t.Errorf("новые блоки или настройки: получено %q, ожидалось C", got)
+	}
+
+	// Без кэша страница всегда читается с диска
+	var none *pageCache
+	setTitle("D")
+	if got := load(none); got != "D" {
+		t.Errorf("без кэша: получено %q, ожидалось D", got)
+	}
+}
+
+func TestSiteWatcherApply(t *testing.T) {
+	root := t.TempDir()
+	contentDir := filepath.Join(root, "content")
+	pageDir := filepath.Join(contentDir, "a")
+	titleFile := filepath.Join(pageDir, "title.val")
+	configPath := filepath.Join(root, "goferret.toml")
+	write := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(titleFile, "A")
+	write(configPath, "[build]\noutput = \"public\"\n")
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{Root: root, ContentDir: contentDir, ConfigPath: configPath, outputFlag: "build"}
+	opts.applyConfig(cfg)
+
+	w := newSiteWatcher(opts, 0, 0)
+	w.cache.reset("v1")
+	load := func() string {
+		model, err := w.cache.load(contentDir, pageDir, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return model.Data["title"]
+	}
+	load()
+
+	// Правка страницы и ошибка в конфигурации в одной серии изменений
+	write(titleFile, "B")
+	write(configPath, "[build\n")
+	if err := w.apply([]string{configPath, titleFile}); err == nil {
+		t.Error("ошибка конфигурации не возвращена")
+	}
+	if got := load(); got != "B" {
+		t.Errorf("правка страницы потеряна: получено %q, ожидалось B", got)
+	}
+	if want := filepath.Join(root, "public"); opts.OutputDir != want {
+		t.Errorf("OutputDir = %q, ожидалось прежнее %q", opts.OutputDir, want)
+	}
+
+	// Исправленная конфигурация применяется, а директория сборки вычисляется заново
+	write(configPath, "[build]\noutput = \"site\"\n")
+	if err := w.apply([]string{configPath}); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, "site"); opts.OutputDir != want {
+		t.Errorf("OutputDir = %q, ожидалось %q", opts.OutputDir, want)
+	}
+}